@@ -0,0 +1,151 @@
+package chew
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChewableBuilder builds a Chewable from Go values, so generators using chew as a library don't have to
+// construct maps by hand. Values are converted with ToMap, which means structs can be used as data:
+//
+//	chewable, err := chew.NewChewable().
+//		WithTemplate(template).
+//		Global(config).
+//		Add(map[string]string{"entity": "user.go"}, user).
+//		Build()
+//
+// Errors are collected and returned by Build, so calls can be chained.
+type ChewableBuilder struct {
+	chewable Chewable
+	template *Template
+	errs     []string
+}
+
+// NewChewable creates an empty ChewableBuilder.
+func NewChewable() *ChewableBuilder {
+	return &ChewableBuilder{
+		chewable: Chewable{
+			Global: make(map[string]interface{}),
+		},
+	}
+}
+
+// WithTemplate sets the template against which the template names used in entries and rules are
+// validated in Build.
+func (b *ChewableBuilder) WithTemplate(ct *Template) *ChewableBuilder {
+	b.template = ct
+	return b
+}
+
+// Global adds the fields of the value to Chewable.Global. Existing fields are overwritten.
+func (b *ChewableBuilder) Global(v interface{}) *ChewableBuilder {
+	global, err := ToMap(v)
+	if err != nil {
+		b.errorf("Could not add global data: %v", err)
+		return b
+	}
+	for k, v := range global {
+		b.chewable.Global[k] = v
+	}
+	return b
+}
+
+// Set stores the value in Chewable.Global on the provided path (see Chewable.Set).
+func (b *ChewableBuilder) Set(path string, value interface{}) *ChewableBuilder {
+	if err := b.chewable.Set(path, value); err != nil {
+		b.errorf("%v", err)
+	}
+	return b
+}
+
+// Add adds an entry which executes the templates (the keys of the map) with the provided data and writes
+// the output to the filenames (the values of the map). The data can be nil.
+func (b *ChewableBuilder) Add(templates map[string]string, data interface{}) *ChewableBuilder {
+	local := make(map[string]interface{})
+	if data != nil {
+		dataMap, err := ToMap(data)
+		if err != nil {
+			b.errorf("Could not add object %d: %v", len(b.chewable.Data), err)
+			return b
+		}
+		for k, v := range dataMap {
+			local[k] = v
+		}
+	}
+
+	return b.AddData(ChewableData{
+		Templates: templates,
+		Local:     local,
+	})
+}
+
+// AddData adds a ChewableData, which makes it possible to define conditions and a matrix.
+func (b *ChewableBuilder) AddData(cd ChewableData) *ChewableBuilder {
+	if len(cd.Templates) == 0 {
+		b.errorf("Could not add object %d: no templates defined", len(b.chewable.Data))
+	}
+	b.chewable.Data = append(b.chewable.Data, cd)
+	return b
+}
+
+// Rule adds a rule (see Rule).
+func (b *ChewableBuilder) Rule(rule Rule) *ChewableBuilder {
+	if rule.For == "" {
+		b.errorf("Could not add rule %d: field 'for' is empty", len(b.chewable.Rules))
+	} else if rule.Template == "" {
+		b.errorf("Could not add rule %d: field 'template' is empty", len(b.chewable.Rules))
+	}
+	b.chewable.Rules = append(b.chewable.Rules, rule)
+	return b
+}
+
+// Build returns the built Chewable. If any of the previous calls failed, or a template name is not
+// defined in the template set with WithTemplate, an error containing all problems is returned.
+func (b *ChewableBuilder) Build() (*Chewable, error) {
+	errs := append([]string(nil), b.errs...)
+	if len(b.chewable.Data) == 0 && len(b.chewable.Rules) == 0 {
+		errs = append(errs, "No data or rules defined")
+	}
+
+	if b.template != nil {
+		for i, cd := range b.chewable.Data {
+			var missing []string
+			for tmpl := range cd.Templates {
+				if !b.template.hasTemplate(tmpl) {
+					missing = append(missing, tmpl)
+				}
+			}
+			sort.Strings(missing)
+			for _, tmpl := range missing {
+				errs = append(errs, fmt.Sprintf("Template '%s' in object %d is not defined", tmpl, i))
+			}
+		}
+		for i, rule := range b.chewable.Rules {
+			if rule.Template != "" && !b.template.hasTemplate(rule.Template) {
+				errs = append(errs, fmt.Sprintf("Template '%s' in rule %d is not defined", rule.Template, i))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New("Could not build chewable: " + strings.Join(errs, "; "))
+	}
+
+	chewable := b.chewable
+	return &chewable, nil
+}
+
+func (b *ChewableBuilder) errorf(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Sprintf(format, args...))
+}
+
+// hasTemplate returns true if a template with the provided name (without the template suffix) is defined.
+func (ct *Template) hasTemplate(name string) bool {
+	if ct.Lookup(name+templateSuffix) != nil {
+		return true
+	}
+	_, ok := ct.layouts[name+templateSuffix]
+	return ok
+}
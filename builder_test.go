@@ -0,0 +1,54 @@
+package chew
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type builderEntity struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+func TestChewableBuilder(t *testing.T) {
+	template := New("main")
+	_, err := template.Parse(`{{ define "entity.tmpl" }}{{ .package }}.{{ .name }}({{ range .columns }}{{ . }},{{ end }});{{ end }}`)
+	assert.NoError(t, err)
+
+	chewable, err := NewChewable().
+		WithTemplate(template).
+		Global(map[string]interface{}{"package": "model"}).
+		Add(map[string]string{"entity": "user.go"}, builderEntity{Name: "User", Columns: []string{"id", "email"}}).
+		Add(map[string]string{"entity": "order.go"}, &builderEntity{Name: "Order", Columns: []string{"id"}}).
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "model", chewable.Global["package"])
+	assert.Len(t, chewable.Data, 2)
+
+	buffer := new(bytes.Buffer)
+	err = template.ExecuteChewable(WriterWrapper{buffer}, *chewable)
+	assert.NoError(t, err)
+	assert.Equal(t, "model.User(id,email,);model.Order(id,);", buffer.String())
+}
+
+func TestChewableBuilder_Errors(t *testing.T) {
+	template := New("main")
+	_, err := template.Parse(`{{ define "entity.tmpl" }}{{ .name }}{{ end }}`)
+	assert.NoError(t, err)
+
+	_, err = NewChewable().
+		WithTemplate(template).
+		Global("not an object").
+		Add(map[string]string{"missing": "missing.go"}, nil).
+		Rule(Rule{For: "entities", Template: "other"}).
+		Build()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not add global data")
+	assert.Contains(t, err.Error(), "Template 'missing' in object 0 is not defined")
+	assert.Contains(t, err.Error(), "Template 'other' in rule 0 is not defined")
+
+	_, err = NewChewable().Build()
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigPath is the path of the project file which is read by the command run.
+const DefaultConfigPath = "chew.yaml"
+
+// Config is the project file (chew.yaml) which describes generation jobs as named targets. The fields
+// defined at the top level are defaults for all targets, e.g.:
+//
+//	templates: [templates/common]
+//	vars:
+//	  project: shop
+//	targets:
+//	  api:
+//	    templates: [templates/common, templates/api]
+//	    data: api/openapi.yaml
+//	    data_format: openapi
+//	    out: gen/api
+//	    entry_templates: ["operations:handler={{ .id }}.go"]
+//	    post: [[gofmt, -w, gen/api]]
+//	  db:
+//	    data: db/schema.sqlite
+//	    out: gen/db
+//	    vars: {db.schema: public}
+//
+// Relative paths are resolved relative to the folder of the project file.
+type Config struct {
+	Target  `yaml:",inline"`
+	Targets map[string]Target `yaml:"targets"`
+
+	// dir is the folder of the project file
+	dir string
+}
+
+// Target is a generation job, its fields correspond to the flags of the root command. Vars are set as global
+// values in the data (like --set) and Post contains commands which are executed after generating, in the
+// folder of the project file. Boolean options are pointers, so a target can also turn off an option which
+// is turned on at the top level (e.g. allow_exec: false).
+type Target struct {
+	Templates   []string               `yaml:"templates"`
+	Data        string                 `yaml:"data"`
+	DataFormat  string                 `yaml:"data_format"`
+	Out         string                 `yaml:"out"`
+	Schema      string                 `yaml:"schema"`
+	IDField     string                 `yaml:"id_field"`
+	Vars        map[string]interface{} `yaml:"vars"`
+	Env         *bool                  `yaml:"env"`
+	AllowEnv    []string               `yaml:"allow_env"`
+	Transform   []string               `yaml:"transform"`
+	AllowExec   *bool                  `yaml:"allow_exec"`
+	ExecTimeout time.Duration          `yaml:"exec_timeout"`
+	ExecDir     string                 `yaml:"exec_dir"`
+
+	EntryTemplates []string `yaml:"entry_templates"`
+	TypePatterns   []string `yaml:"type_patterns"`
+	TypeMarked     *bool    `yaml:"type_marked"`
+	ProtoFiles     []string `yaml:"proto_files"`
+
+	Post [][]string `yaml:"post"`
+}
+
+// LoadConfig reads the project file with the provided path.
+func LoadConfig(path string) (*Config, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(raw, config); err != nil {
+		return nil, fmt.Errorf("Could not parse %s: %v", path, err)
+	}
+	if len(config.Targets) == 0 {
+		return nil, fmt.Errorf("Could not find any targets in %s", path)
+	}
+
+	config.dir = filepath.Dir(path)
+	return config, nil
+}
+
+// TargetNames returns the names of all targets in alphabetical order.
+func (c *Config) TargetNames() []string {
+	names := make([]string, 0, len(c.Targets))
+	for name := range c.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the target with the provided name, fields which are not defined in the target are taken
+// from the top level of the project file. Vars are merged, vars of the target override the top level vars.
+// Relative paths are resolved relative to the folder of the project file.
+func (c *Config) Resolve(name string) (Target, error) {
+	target, ok := c.Targets[name]
+	if !ok {
+		return Target{}, fmt.Errorf("Unknown target '%s'", name)
+	}
+
+	tv := reflect.ValueOf(&target).Elem()
+	dv := reflect.ValueOf(c.Target)
+	for i := 0; i < tv.NumField(); i++ {
+		if field := tv.Field(i); field.IsZero() {
+			field.Set(dv.Field(i))
+		}
+	}
+
+	vars := make(map[string]interface{}, len(c.Vars)+len(target.Vars))
+	for path, value := range c.Vars {
+		vars[path] = value
+	}
+	for path, value := range c.Targets[name].Vars {
+		vars[path] = value
+	}
+	target.Vars = vars
+
+	target.Templates = c.paths(target.Templates)
+	target.Data = c.path(target.Data)
+	target.Out = c.path(target.Out)
+	target.Schema = c.path(target.Schema)
+	target.ExecDir = c.path(target.ExecDir)
+
+	if target.Data == "" {
+		return target, fmt.Errorf("Target '%s' has no data", name)
+	} else if len(target.Templates) == 0 {
+		return target, fmt.Errorf("Target '%s' has no templates", name)
+	} else if target.Out == "" {
+		return target, fmt.Errorf("Target '%s' has no output folder", name)
+	}
+	return target, nil
+}
+
+// apply sets the flags of the root command as defined in the target.
+func (t Target) apply() error {
+	templatesPaths = t.Templates
+	dataPath = t.Data
+	dataFormat = t.DataFormat
+	outPath = t.Out
+	schemaPath = t.Schema
+	idField = t.IDField
+	if idField == "" {
+		idField = "id"
+	}
+	interpolateEnv = isTrue(t.Env)
+	envAllowlist = t.AllowEnv
+	transformations = t.Transform
+	allowExec = isTrue(t.AllowExec)
+	execTimeout = t.ExecTimeout
+	execDir = t.ExecDir
+
+	entryTemplates = t.EntryTemplates
+	typePatterns = t.TypePatterns
+	typeMarked = isTrue(t.TypeMarked)
+	protoFiles = t.ProtoFiles
+
+	setValues = nil
+	for _, path := range sortedVars(t.Vars) {
+		value, err := json.Marshal(t.Vars[path])
+		if err != nil {
+			return fmt.Errorf("Could not set var '%s': %v", path, err)
+		}
+		setValues = append(setValues, path+"="+string(value))
+	}
+	return nil
+}
+
+// isTrue returns true if the option is set and true.
+func isTrue(option *bool) bool {
+	return option != nil && *option
+}
+
+func sortedVars(vars map[string]interface{}) []string {
+	paths := make([]string, 0, len(vars))
+	for path := range vars {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// path returns the path relative to the folder of the project file.
+func (c *Config) path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
+
+func (c *Config) paths(paths []string) []string {
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		resolved[i] = c.path(path)
+	}
+	return resolved
+}
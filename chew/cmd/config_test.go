@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+templates: [templates/common]
+data: data.json
+env: true
+allow_exec: true
+exec_timeout: 5s
+vars:
+  project: shop
+  db.schema: public
+targets:
+  api:
+    templates: [templates/common, /abs/templates/api]
+    data: api/openapi.yaml
+    data_format: openapi
+    out: gen/api
+    entry_templates: ["operations:handler={{ .id }}.go"]
+  db:
+    out: gen/db
+    allow_exec: false
+    env: false
+    vars: {db.schema: app, owner: admin}
+    post: [[gofmt, -w, gen/db]]
+  no-out:
+    data: other.json
+`
+
+func writeConfig(t *testing.T, content string) *Config {
+	path := filepath.Join(t.TempDir(), DefaultConfigPath)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	return config
+}
+
+func TestConfig_Resolve(t *testing.T) {
+	config := writeConfig(t, testConfig)
+	dir := config.dir
+	yes, no := true, false
+
+	testCases := []struct {
+		Name     string
+		Expected Target
+		Err      string
+	}{
+		{
+			Name: "api",
+			Expected: Target{
+				Templates:      []string{filepath.Join(dir, "templates/common"), "/abs/templates/api"},
+				Data:           filepath.Join(dir, "api/openapi.yaml"),
+				DataFormat:     "openapi",
+				Out:            filepath.Join(dir, "gen/api"),
+				Vars:           map[string]interface{}{"project": "shop", "db.schema": "public"},
+				Env:            &yes,
+				AllowExec:      &yes,
+				ExecTimeout:    5 * time.Second,
+				EntryTemplates: []string{"operations:handler={{ .id }}.go"},
+			},
+		},
+		{
+			Name: "db",
+			Expected: Target{
+				Templates:   []string{filepath.Join(dir, "templates/common")},
+				Data:        filepath.Join(dir, "data.json"),
+				Out:         filepath.Join(dir, "gen/db"),
+				Vars:        map[string]interface{}{"project": "shop", "db.schema": "app", "owner": "admin"},
+				Env:         &no,
+				AllowExec:   &no,
+				ExecTimeout: 5 * time.Second,
+				Post:        [][]string{{"gofmt", "-w", "gen/db"}},
+			},
+		},
+		{Name: "no-out", Err: "Target 'no-out' has no output folder"},
+		{Name: "missing", Err: "Unknown target 'missing'"},
+	}
+
+	for _, tc := range testCases {
+		target, err := config.Resolve(tc.Name)
+		if tc.Err != "" {
+			assert.EqualError(t, err, tc.Err, tc.Name)
+			continue
+		}
+		assert.NoError(t, err, tc.Name)
+		assert.Equal(t, tc.Expected, target, tc.Name)
+	}
+}
+
+func TestConfig_Resolve_Missing(t *testing.T) {
+	testCases := []struct {
+		Config string
+		Err    string
+	}{
+		{"targets: {a: {templates: [t], out: o}}", "Target 'a' has no data"},
+		{"targets: {a: {data: d.json, out: o}}", "Target 'a' has no templates"},
+		{"targets: {a: {data: d.json, templates: [t]}}", "Target 'a' has no output folder"},
+		{"data: d.json\nout: o\ntemplates: [t]\ntargets: {a: {}}", ""},
+	}
+
+	for _, tc := range testCases {
+		_, err := writeConfig(t, tc.Config).Resolve("a")
+		if tc.Err == "" {
+			assert.NoError(t, err, tc.Config)
+		} else {
+			assert.EqualError(t, err, tc.Err, tc.Config)
+		}
+	}
+}
+
+func TestLoadConfig_Error(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultConfigPath)
+	require.NoError(t, ioutil.WriteFile(path, []byte("templates: [t]"), 0644))
+	_, err := LoadConfig(path)
+	assert.EqualError(t, err, "Could not find any targets in "+path)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestTarget_Apply(t *testing.T) {
+	yes, no := true, false
+	testCases := []struct {
+		Target    Target
+		IDField   string
+		SetValues []string
+		Env       bool
+		AllowExec bool
+		Marked    bool
+	}{
+		{
+			Target:  Target{},
+			IDField: "id",
+		},
+		{
+			Target: Target{
+				IDField:    "key",
+				Vars:       map[string]interface{}{"project": "shop", "db.port": 5432, "tags": []interface{}{"a"}},
+				Env:        &yes,
+				AllowExec:  &yes,
+				TypeMarked: &yes,
+			},
+			IDField:   "key",
+			SetValues: []string{"db.port=5432", `project="shop"`, `tags=["a"]`},
+			Env:       true,
+			AllowExec: true,
+			Marked:    true,
+		},
+		{
+			Target:  Target{Env: &no, AllowExec: &no, TypeMarked: &no},
+			IDField: "id",
+		},
+	}
+
+	for i, tc := range testCases {
+		// flags set by a previous target are overwritten
+		interpolateEnv, allowExec, typeMarked, setValues = true, true, true, []string{"old=1"}
+
+		assert.NoError(t, tc.Target.apply(), i)
+		assert.Equal(t, tc.IDField, idField, i)
+		assert.Equal(t, tc.SetValues, setValues, i)
+		assert.Equal(t, tc.Env, interpolateEnv, i)
+		assert.Equal(t, tc.AllowExec, allowExec, i)
+		assert.Equal(t, tc.Marked, typeMarked, i)
+	}
+
+	target := Target{
+		Templates:      []string{"t"},
+		Data:           "d.json",
+		DataFormat:     "yaml",
+		Out:            "o",
+		Schema:         "s.json",
+		AllowEnv:       []string{"HOME"},
+		Transform:      []string{".a = 1"},
+		ExecTimeout:    time.Second,
+		ExecDir:        "exec",
+		EntryTemplates: []string{"tables:dao="},
+		TypePatterns:   []string{"*Service"},
+		ProtoFiles:     []string{"a.proto"},
+	}
+	assert.NoError(t, target.apply())
+	assert.Equal(t, []string{"t"}, templatesPaths)
+	assert.Equal(t, "d.json", dataPath)
+	assert.Equal(t, "yaml", dataFormat)
+	assert.Equal(t, "o", outPath)
+	assert.Equal(t, "s.json", schemaPath)
+	assert.Equal(t, []string{"HOME"}, envAllowlist)
+	assert.Equal(t, []string{".a = 1"}, transformations)
+	assert.Equal(t, time.Second, execTimeout)
+	assert.Equal(t, "exec", execDir)
+	assert.Equal(t, []string{"tables:dao="}, entryTemplates)
+	assert.Equal(t, []string{"*Service"}, typePatterns)
+	assert.Equal(t, []string{"a.proto"}, protoFiles)
+
+	assert.Error(t, Target{Vars: map[string]interface{}{"f": func() {}}}.apply())
+}
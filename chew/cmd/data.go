@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lovromazgon/chew"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	RootCmd.AddCommand(dataCmd)
+
+	dataCmd.Flags().StringVarP(&dataPath, "data", "d", "", "Path to input file with data (JSON, YAML, CSV, TSV, XML, SQLite database, Go source file, OpenAPI document with --data-format openapi or protobuf descriptor set)")
+	dataCmd.Flags().StringVar(&dataFormat, "data-format", "", "Format of the data file (e.g. yaml, csv or openapi), overrides the format defined by its extension")
+	dataCmd.Flags().StringVarP(&schemaPath, "schema", "s", "", "Path to JSON Schema file used to validate the data (overrides $schema in the data)")
+	dataCmd.Flags().StringArrayVar(&entryTemplates, "entry-template", nil, "Template executed for every element of a list in the data (list:template=out, e.g. 'tables:dao={{ .name }}.go') or for every object in 'data' (template=out), can be repeated")
+	dataCmd.Flags().StringSliceVar(&typePatterns, "type-pattern", nil, "Select Go types with names matching the pattern (e.g. '*Service')")
+	dataCmd.Flags().BoolVar(&typeMarked, "type-marked", false, "Select Go types with a marker comment (//chew:...)")
+	dataCmd.Flags().StringSliceVar(&protoFiles, "proto-file", nil, "Use only the listed files of a protobuf descriptor set (e.g. shop/order.proto)")
+	dataCmd.Flags().BoolVar(&allowExec, "allow-exec", false, "Allow $exec in the data to execute commands, whose output is inserted into the data")
+	dataCmd.Flags().DurationVar(&execTimeout, "exec-timeout", chew.DefaultExecTimeout, "Maximum duration of a command executed with $exec")
+	dataCmd.Flags().StringVar(&execDir, "exec-dir", "", "Working directory of commands executed with $exec (default is the folder of the data file)")
+	dataCmd.Flags().StringArrayVar(&setValues, "set", nil, "Set a global value in the data (key.path=value), can be repeated")
+	dataCmd.Flags().BoolVar(&interpolateEnv, "env", false, "Replace ${VAR} references in string values in the data with environment variables")
+	dataCmd.Flags().StringArrayVar(&transformations, "transform", nil, "Transform the data with a query before generating, can be repeated")
+	dataCmd.Flags().BoolVarP(&printData, "print", "p", false, "Print the effective data")
+	dataCmd.Flags().StringVarP(&printFormat, "format", "f", "json", "Format of the printed data (json or yaml)")
+	dataCmd.Flags().BoolVar(&expandData, "expand", true, "Expand rules and matrices before printing the data")
+
+	dataCmd.MarkFlagFilename("data", "json", "yaml", "yml", "csv", "tsv", "xml", "db", "sqlite", "sqlite3", "go", "pb", "binpb", "desc", "protoset")
+	dataCmd.MarkFlagRequired("data")
+}
+
+var dataCmd = &cobra.Command{
+	Use:   "data",
+	Short: "Load, validate and print the data",
+	Long: `Loads the data the same way as when generating: includes and references are resolved,
+environment variables are interpolated (--env), values are set (--set), transformations are applied
+(--transform) and the data is validated with the JSON Schema. With --print the effective data is
+printed, by default with expanded rules and matrices, exactly as it is passed to the templates.`,
+	RunE: dataRun,
+}
+
+// ----------------------------------------------------------------
+
+var (
+	printData   bool
+	printFormat string
+	expandData  bool
+)
+
+func dataRun(cmd *cobra.Command, args []string) error {
+	if dataPath == "" {
+		return errors.New("Data flag is required!")
+	} else if printFormat != "json" && printFormat != "yaml" {
+		return fmt.Errorf("Unknown format '%s', expected json or yaml", printFormat)
+	}
+
+	chewable, err := loadChewable()
+	if err != nil {
+		return err
+	}
+	if expandData {
+		expanded, err := chewable.Expand()
+		if err != nil {
+			return err
+		}
+		chewable = &expanded
+	}
+
+	if !printData {
+		return nil
+	}
+
+	if printFormat == "yaml" {
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(chewable); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(chewable)
+}
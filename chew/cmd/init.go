@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/lovromazgon/chew"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringVar(&initFrom, "from", "", "Path to a template pack (.zip, .tar.gz) whose templates are used instead of the example templates")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite existing files")
+
+	initCmd.MarkFlagFilename("from", "zip", "tar.gz", "tgz")
+}
+
+var initCmd = &cobra.Command{
+	Use:   "init [folder]",
+	Short: "Create a starter project",
+	Long: `Creates a starter project in the folder (the current folder by default): a project file
+(chew.yaml), a sample data file (data.json), a templates folder and a README which explains
+the layout of the data. The example templates show how to use indentTemplate and plugins.
+With --from the templates are extracted from an existing template pack instead and the sample
+data executes the entry templates of the pack (listed in the field 'templates' of its manifest,
+by default all templates except layouts and templates executed by other templates). Existing files are not overwritten without --force.
+The project can be generated with 'chew run'.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: initRun,
+}
+
+// ----------------------------------------------------------------
+
+var (
+	initFrom  string
+	initForce bool
+)
+
+const initConfig = `# Chew project file, run all targets with 'chew run' or a single target with 'chew run model'
+templates: [templates]
+targets:
+  model:
+    data: data.json
+    out: out
+    # commands executed after generating, e.g. to format the generated code
+    # post: [[gofmt, -w, out]]
+`
+
+const initData = `{
+  "package": "model",
+  "data": [
+    {
+      "templates": {"model": "{{ .name }}.go"},
+      "name": "User",
+      "fields": [
+        {"name": "ID", "type": "int64"},
+        {"name": "Email", "type": "string"}
+      ],
+      "plugins": [
+        {"name": "Email", "template": {"validate": "validate_required"}}
+      ]
+    },
+    {
+      "templates": {"model": "{{ .name }}.go"},
+      "name": "Order",
+      "fields": [
+        {"name": "ID", "type": "int64"},
+        {"name": "UserID", "type": "int64"}
+      ],
+      "plugins": []
+    }
+  ]
+}
+`
+
+var initTemplates = map[string]string{
+	"model.tmpl": `// Code generated by chew. DO NOT EDIT.
+
+package {{ .package }}
+{{ if .plugins }}
+import "errors"
+{{ end }}
+// {{ .name }} is generated from the data object with the name {{ .name }}.
+type {{ .name }} struct {
+{{- range .fields }}
+{{ indentTemplate "field" . $ 4 }}
+{{- end }}
+}
+
+// Validate validates the fields of {{ .name }}.
+func (m *{{ .name }}) Validate() error {
+{{- if .plugins }}
+{{ plugins .plugins "validate" "template" $ 4 }}
+{{- end }}
+    return nil
+}
+`,
+	"field.tmpl": `{{ .name }} {{ .type }} // field of {{ .parent.name }}`,
+	"validate_required.tmpl": `if m.{{ .name }} == "" {
+    return errors.New("{{ .parent.name }}.{{ .name }} is required")
+}`,
+}
+
+const initReadme = `# Code generation
+
+The code in the folder out is generated with [Chew](https://github.com/lovromazgon/chew) from
+the data in data.json and the templates in the folder templates, as configured in chew.yaml.
+Regenerate it with:
+
+    chew run
+
+## Data
+
+The top-level fields of data.json (except 'data') are global, they are available in every template
+(e.g. {{ .package }}). Every object in the field 'data' executes the templates in its field
+'templates', which maps the name of a template (the file name without .tmpl) to the output file
+(e.g. {"model": "User.go"}). The output file can itself be a template (e.g. "{{ .name }}.go").
+All other fields of the object are local, they are available only in the templates it executes
+(e.g. {{ .name }}).
+
+Print the data as it is passed to the templates with 'chew data -d data.json -p'.
+
+## Templates
+
+Templates are Go templates with additional functions, list them with 'chew functions'. List the
+available templates with 'chew templates -t templates'.
+`
+
+// initReadmeExamples is added to the README if the example templates are created.
+const initReadmeExamples = `
+Templates can execute other templates with indentTemplate (see model.tmpl and field.tmpl) and insert
+plugins, which are defined in the data for named insertion points (see the field 'plugins' in data.json
+and validate_required.tmpl).
+`
+
+func initRun(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	data, readme := initData, initReadme+initReadmeExamples
+	templatesDir := filepath.Join(dir, "templates")
+	if initFrom != "" {
+		var err error
+		if data, err = initFromPack(templatesDir); err != nil {
+			return err
+		}
+		readme = initReadme
+	} else {
+		names := make([]string, 0, len(initTemplates))
+		for name := range initTemplates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := initFile(filepath.Join(templatesDir, name), initTemplates[name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := initFile(filepath.Join(dir, DefaultConfigPath), initConfig); err != nil {
+		return err
+	}
+	if err := initFile(filepath.Join(dir, "data.json"), data); err != nil {
+		return err
+	}
+	return initFile(filepath.Join(dir, "README.md"), readme)
+}
+
+// initFromPack extracts the template pack into the templates folder and returns sample data which
+// executes the entry templates of the pack. The entry templates are listed in the manifest of the pack,
+// without them all templates except layouts and templates executed by other templates are used (see
+// chew.Template.EntryTemplates). Templates which can't be executed with the sample data are left out.
+func initFromPack(templatesDir string) (string, error) {
+	var pack *chew.Pack
+	if _, err := os.Stat(templatesDir); err == nil && !initForce {
+		fmt.Printf("Skipped %s (already exists)\n", templatesDir)
+	} else {
+		if pack, err = chew.ExtractPack(initFrom, templatesDir); err != nil {
+			return "", err
+		}
+		if pack != nil {
+			fmt.Printf("Extracted template pack %s %s into %s\n", pack.Name, pack.Version, templatesDir)
+		} else {
+			fmt.Printf("Extracted template pack %s into %s\n", initFrom, templatesDir)
+		}
+	}
+
+	template := chew.New("main")
+	if _, err := template.ParseFolder(templatesDir); err != nil {
+		return "", err
+	}
+	names := template.EntryTemplates()
+	if pack != nil && len(pack.Templates) > 0 {
+		names = pack.Templates
+	}
+
+	local := map[string]interface{}{"name": "example"}
+	templates := make(map[string]string)
+	for _, name := range names {
+		out := name + ".out"
+		if meta := template.Meta(name); meta != nil && meta.Output != "" {
+			// the output filename is defined in the front matter
+			out = ""
+		}
+
+		sample := chew.Chewable{Data: []chew.ChewableData{{Templates: map[string]string{name: out}, Local: local}}}
+		if err := template.ExecuteChewable(&chew.MemoryWriter{}, sample); err != nil {
+			fmt.Printf("Skipped template %s in the sample data: %v\n", name, err)
+			continue
+		}
+		templates[name] = out
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{
+				"templates": templates,
+				"name":      "example",
+			},
+		},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// initFile writes the file, existing files are only overwritten with --force.
+func initFile(path, content string) error {
+	if _, err := os.Stat(path); err == nil && !initForce {
+		fmt.Printf("Skipped %s (already exists)\n", path)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Created %s\n", path)
+	return nil
+}
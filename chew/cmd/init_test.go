@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lovromazgon/chew"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePack writes a zip template pack containing the templates in the folders and the additional files.
+func writePack(t *testing.T, path string, folders []string, files map[string]string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	write := func(name string, content []byte) {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(content)
+		require.NoError(t, err)
+	}
+	for _, folder := range folders {
+		paths, err := filepath.Glob(filepath.Join(folder, "*.tmpl"))
+		require.NoError(t, err)
+		for _, path := range paths {
+			content, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			write(filepath.Base(path), content)
+		}
+	}
+	for name, content := range files {
+		write(name, []byte(content))
+	}
+	require.NoError(t, zw.Close())
+}
+
+// runInit runs the command init in the folder and returns the generated data.
+func runInit(t *testing.T, dir, from string) *chew.Chewable {
+	defer func() { initFrom = "" }()
+	initFrom = from
+
+	require.NoError(t, initRun(initCmd, []string{dir}))
+	chewable, err := chew.LoadChewable(filepath.Join(dir, "data.json"))
+	require.NoError(t, err)
+	return chewable
+}
+
+// generate executes the data with the templates in the folder templates of the project.
+func generate(t *testing.T, dir string, chewable *chew.Chewable) []string {
+	template := chew.New("main")
+	_, err := template.ParseFolder(filepath.Join(dir, "templates"))
+	require.NoError(t, err)
+
+	w := &chew.MemoryWriter{}
+	require.NoError(t, template.ExecuteChewable(w, *chewable))
+	return w.Files()
+}
+
+func TestInitRun(t *testing.T) {
+	dir := t.TempDir()
+	chewable := runInit(t, dir, "")
+
+	for _, name := range []string{DefaultConfigPath, "README.md", "templates/model.tmpl", "templates/field.tmpl", "templates/validate_required.tmpl"} {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	assert.ElementsMatch(t, []string{"User.go", "Order.go"}, generate(t, dir, chewable))
+}
+
+func TestInitRun_Existing(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, DefaultConfigPath)
+	require.NoError(t, ioutil.WriteFile(config, []byte("custom"), 0644))
+
+	runInit(t, dir, "")
+	content, err := ioutil.ReadFile(config)
+	require.NoError(t, err)
+	assert.Equal(t, "custom", string(content))
+
+	defer func() { initForce = false }()
+	initForce = true
+	runInit(t, dir, "")
+	content, err = ioutil.ReadFile(config)
+	require.NoError(t, err)
+	assert.Equal(t, initConfig, string(content))
+}
+
+func TestInitRun_FromPack(t *testing.T) {
+	dir := t.TempDir()
+	packPath := filepath.Join(dir, "pack.zip")
+	writePack(t, packPath, []string{"../../test/templates", "../../test/layouts"}, map[string]string{
+		"page_e.tmpl":    "---chew\noutput: \"{{ .name }}.txt\"\n---\nPage {{ .name }}\n{{ indentTemplate \"partial\" . $ 2 }}",
+		"partial.tmpl":   "Partial of {{ .parent.name }}",
+		"page_f.tmpl":    "{{ template \"included.tmpl\" . }}",
+		"included.tmpl":  "Included in {{ .name }}",
+		"required.tmpl":  "---chew\nrequired: {title: string}\n---\n{{ .title }}",
+		"chew-pack.json": `{"name":"pages","version":"1.0.0"}`,
+	})
+
+	project := filepath.Join(dir, "project")
+	chewable := runInit(t, project, packPath)
+
+	// layouts, templates executed by other templates and templates which fail with the sample data
+	// (test_plugins_main, test_indentTemplate, page_d, required) are left out
+	require.Len(t, chewable.Data, 1)
+	assert.Equal(t, map[string]string{
+		"page_a":                   "page_a.out",
+		"page_b":                   "page_b.out",
+		"page_c":                   "page_c.out",
+		"page_e":                   "",
+		"page_f":                   "page_f.out",
+		"test_plugins_plugin1_ger": "test_plugins_plugin1_ger.out",
+		"test_plugins_plugin1_ita": "test_plugins_plugin1_ita.out",
+		"test_plugins_plugin2":     "test_plugins_plugin2.out",
+	}, chewable.Data[0].Templates)
+
+	assert.ElementsMatch(t, []string{
+		"page_a.out", "page_b.out", "page_c.out", "example.txt", "page_f.out",
+		"test_plugins_plugin1_ger.out", "test_plugins_plugin1_ita.out", "test_plugins_plugin2.out",
+	}, generate(t, project, chewable))
+}
+
+func TestInitRun_FromPackManifest(t *testing.T) {
+	dir := t.TempDir()
+	packPath := filepath.Join(dir, "pack.zip")
+	writePack(t, packPath, []string{"../../test/templates", "../../test/layouts"}, map[string]string{
+		"chew-pack.json": `{"name":"pages","version":"1.0.0","templates":["page_a","page_c","test_plugins_main"]}`,
+	})
+
+	project := filepath.Join(dir, "project")
+	chewable := runInit(t, project, packPath)
+
+	require.Len(t, chewable.Data, 1)
+	assert.Equal(t, map[string]string{"page_a": "page_a.out", "page_c": "page_c.out"}, chewable.Data[0].Templates)
+	assert.ElementsMatch(t, []string{"page_a.out", "page_c.out"}, generate(t, project, chewable))
+}
+
+func TestInitRun_FromUnsafePack(t *testing.T) {
+	dir := t.TempDir()
+	packPath := filepath.Join(dir, "pack.zip")
+	writePack(t, packPath, nil, map[string]string{
+		"a.tmpl":               "a",
+		"z/../../escaped.tmpl": "escaped",
+	})
+
+	defer func() { initFrom = "" }()
+	initFrom = packPath
+	project := filepath.Join(dir, "project")
+	assert.Error(t, initRun(initCmd, []string{project}))
+
+	// no file of the pack is written
+	_, err := os.Stat(filepath.Join(project, "templates"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(project, "escaped.tmpl"))
+	assert.True(t, os.IsNotExist(err))
+}
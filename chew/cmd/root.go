@@ -18,11 +18,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
-
-	"encoding/json"
-	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/lovromazgon/chew"
+	"github.com/lovromazgon/chew/source/gosource"
+	"github.com/lovromazgon/chew/source/openapi"
+	"github.com/lovromazgon/chew/source/protobuf"
 	"github.com/spf13/cobra"
 )
 
@@ -50,11 +53,26 @@ func init() {
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
-	RootCmd.Flags().StringVarP(&dataPath, "data", "d", "", "Path to input JSON file with data")
-	RootCmd.Flags().StringVarP(&templatesPath, "templates", "t", "", "Path to folder with templates (will be read recursively)")
+	RootCmd.Flags().StringVarP(&dataPath, "data", "d", "", "Path to input file with data (JSON, YAML, CSV, TSV, XML, SQLite database, Go source file, OpenAPI document with --data-format openapi or protobuf descriptor set)")
+	RootCmd.Flags().StringArrayVarP(&templatesPaths, "templates", "t", nil, "Path to folder (read recursively) or template pack (.zip, .tar.gz) with templates, can be repeated to override templates from earlier folders")
 	RootCmd.Flags().StringVarP(&outPath, "out", "o", "", "Path to output folder")
+	RootCmd.Flags().StringVar(&dataFormat, "data-format", "", "Format of the data file (e.g. yaml, csv or openapi), overrides the format defined by its extension")
+	RootCmd.Flags().StringVarP(&schemaPath, "schema", "s", "", "Path to JSON Schema file used to validate the data (overrides $schema in the data)")
+	RootCmd.Flags().StringVar(&idField, "id-field", "id", "Field which identifies data objects in the template function ref")
+	RootCmd.Flags().StringArrayVar(&entryTemplates, "entry-template", nil, "Template executed for every element of a list in the data (list:template=out, e.g. 'tables:dao={{ .name }}.go') or for every object in 'data' (template=out), can be repeated")
+	RootCmd.Flags().StringSliceVar(&typePatterns, "type-pattern", nil, "Select Go types with names matching the pattern (e.g. '*Service')")
+	RootCmd.Flags().BoolVar(&typeMarked, "type-marked", false, "Select Go types with a marker comment (//chew:...)")
+	RootCmd.Flags().StringSliceVar(&protoFiles, "proto-file", nil, "Use only the listed files of a protobuf descriptor set (e.g. shop/order.proto)")
+	RootCmd.Flags().BoolVar(&allowExec, "allow-exec", false, "Allow $exec in the data to execute commands, whose output is inserted into the data")
+	RootCmd.Flags().DurationVar(&execTimeout, "exec-timeout", chew.DefaultExecTimeout, "Maximum duration of a command executed with $exec")
+	RootCmd.Flags().StringVar(&execDir, "exec-dir", "", "Working directory of commands executed with $exec (default is the folder of the data file)")
+	RootCmd.Flags().StringArrayVar(&setValues, "set", nil, "Set a global value in the data (key.path=value), can be repeated")
+	RootCmd.Flags().BoolVar(&interpolateEnv, "env", false, "Replace ${VAR} references in string values in the data with environment variables")
+	RootCmd.Flags().StringSliceVar(&envAllowlist, "allow-env", nil, "Environment variables which can be read with the template function env")
+	RootCmd.Flags().StringArrayVar(&transformations, "transform", nil, "Transform the data with a query before generating (e.g. '.entities = .tables | select(.kind == \"entity\")'), can be repeated")
+	RootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Print skipped data objects and templates")
 
-	RootCmd.MarkFlagFilename("data", ".json")
+	RootCmd.MarkFlagFilename("data", "json", "yaml", "yml", "csv", "tsv", "xml", "db", "sqlite", "sqlite3", "go", "pb", "binpb", "desc", "protoset")
 	RootCmd.MarkFlagRequired("data")
 	RootCmd.MarkFlagRequired("templates")
 	RootCmd.MarkFlagRequired("out")
@@ -63,19 +81,34 @@ func init() {
 // ----------------------------------------------------------------
 
 var (
-	templatesPath string
-	dataPath      string
-	outPath       string
+	templatesPaths  []string
+	dataPath        string
+	dataFormat      string
+	outPath         string
+	schemaPath      string
+	idField         string
+	setValues       []string
+	interpolateEnv  bool
+	envAllowlist    []string
+	transformations []string
+	entryTemplates  []string
+	typePatterns    []string
+	typeMarked      bool
+	protoFiles      []string
+	allowExec       bool
+	execTimeout     time.Duration
+	execDir         string
+	verbose         bool
 )
 
 func preChew(cmd *cobra.Command, args []string) error {
-	if templatesPath == "" {
+	if len(templatesPaths) == 0 {
 		return errors.New("Templates flag is required!")
 	} else if dataPath == "" {
 		return errors.New("Data flag is required!")
 	} else if outPath == "" {
 		return errors.New("Out flag is required!")
-	} else if _, err := os.Stat(templatesPath); err != nil {
+	} else if err := checkTemplatesPaths(); err != nil {
 		return err
 	} else if _, err := os.Stat(dataPath); err != nil {
 		return err
@@ -84,24 +117,171 @@ func preChew(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func chewRun(cmd *cobra.Command, args []string) error {
-	dataRaw, err := ioutil.ReadFile(dataPath)
-	if err != nil {
-		return err
+func checkTemplatesPaths() error {
+	for _, path := range templatesPaths {
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	chewable := &chew.Chewable{}
-
-	err = json.Unmarshal(dataRaw, chewable)
+func chewRun(cmd *cobra.Command, args []string) error {
+	chewable, err := loadChewable()
 	if err != nil {
 		return err
 	}
 
 	template := chew.New("main")
-	_, err = template.ParseFolder(templatesPath)
+	_, err = template.ParseFolders(templatesPaths...)
 	if err != nil {
 		return err
 	}
+	template.IDField = idField
+	template.EnvAllowlist = envAllowlist
+	if verbose {
+		template.Verbose = os.Stderr
+	}
 
 	return template.ExecuteChewable(&chew.MultiFileWriter{Out: outPath}, *chewable)
 }
+
+// loadChewable loads the data file (executing commands only if allowed) and prepares the data as defined by the flags: environment variables
+// are interpolated, values are set, transformations are applied and the result is validated.
+func loadChewable() (*chew.Chewable, error) {
+	rules, templates, err := parseEntryTemplates()
+	if err != nil {
+		return nil, err
+	}
+	registerDecoders(templates)
+
+	chewable, err := chew.LoadChewableWithOptions(dataPath, chew.LoadOptions{
+		Exec:        allowExec,
+		ExecTimeout: execTimeout,
+		ExecDir:     execDir,
+		Format:      dataFormat,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if interpolateEnv {
+		if err := chewable.Interpolate(os.LookupEnv); err != nil {
+			return nil, err
+		}
+	}
+	for _, assignment := range setValues {
+		path, value, err := chew.ParseAssignment(assignment)
+		if err != nil {
+			return nil, err
+		}
+		if err := chewable.Set(path, value); err != nil {
+			return nil, err
+		}
+	}
+	for _, transformation := range transformations {
+		if err := chewable.Transform(transformation); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateChewable(chewable); err != nil {
+		return nil, err
+	}
+	addEntryTemplates(chewable, rules, templates)
+	return chewable, nil
+}
+
+// sqliteExtensions are the extensions of SQLite databases.
+var sqliteExtensions = []string{".db", ".sqlite", ".sqlite3"}
+
+// registerDecoders registers the decoders for CSV, TSV, SQLite, Go, OpenAPI and protobuf descriptor set files with the options
+// defined by the flags. The templates are executed for every row of CSV and TSV files.
+func registerDecoders(templates map[string]string) {
+	chew.RegisterDecoder(".csv", &chew.CSVDecoder{Comma: ',', Templates: templates})
+	chew.RegisterDecoder(".tsv", &chew.CSVDecoder{Comma: '\t', Templates: templates})
+	registerSQLiteDecoder()
+
+	chew.RegisterDecoder(".go", &gosource.Decoder{Options: gosource.Options{
+		Patterns: typePatterns,
+		Marked:   typeMarked,
+	}})
+
+	// OpenAPI documents are ordinary JSON or YAML files, they are only decoded as such with --data-format openapi
+	chew.RegisterDecoder(".openapi", &openapi.Decoder{})
+
+	protobufDecoder := &protobuf.Decoder{Options: protobuf.Options{
+		Files: protoFiles,
+	}}
+	for _, ext := range []string{".pb", ".binpb", ".desc", ".protoset"} {
+		chew.RegisterDecoder(ext, protobufDecoder)
+	}
+}
+
+// parseEntryTemplates parses the templates defined by the flag entry-template. A template with a list
+// (list:template=out) is executed for every element of the list in the global data, e.g. for every table
+// of a SQLite database, and is returned as a rule (see chew.Rule). Templates without a list (template=out)
+// are executed for every object in the field 'data', e.g. for every row of a CSV file.
+func parseEntryTemplates() ([]chew.Rule, map[string]string, error) {
+	var rules []chew.Rule
+	templates := make(map[string]string)
+	for _, mapping := range entryTemplates {
+		i := strings.Index(mapping, "=")
+		if i <= 0 {
+			return nil, nil, fmt.Errorf("Invalid entry template '%s', expected list:template=out or template=out", mapping)
+		}
+		tmpl, out := mapping[:i], mapping[i+1:]
+
+		j := strings.LastIndex(tmpl, ":")
+		if j < 0 {
+			templates[tmpl] = out
+			continue
+		}
+		list := tmpl[:j]
+		if tmpl = tmpl[j+1:]; list == "" || tmpl == "" {
+			return nil, nil, fmt.Errorf("Invalid entry template '%s', expected list:template=out or template=out", mapping)
+		}
+		rules = append(rules, chew.Rule{For: list, Template: tmpl, Out: out})
+	}
+	return rules, templates, nil
+}
+
+// addEntryTemplates adds the rules to the Chewable and the templates to every object in the field 'data'.
+func addEntryTemplates(chewable *chew.Chewable, rules []chew.Rule, templates map[string]string) {
+	chewable.Rules = append(chewable.Rules, rules...)
+	if len(templates) == 0 {
+		return
+	}
+
+	for i, cd := range chewable.Data {
+		cdTemplates := make(map[string]string, len(cd.Templates)+len(templates))
+		for tmpl, out := range cd.Templates {
+			cdTemplates[tmpl] = out
+		}
+		for tmpl, out := range templates {
+			cdTemplates[tmpl] = out
+		}
+		chewable.Data[i].Templates = cdTemplates
+	}
+}
+
+// validateChewable validates the data with the JSON Schema defined by the schema flag or the
+// field $schema in the data. A relative path in $schema is resolved relative to the data file.
+func validateChewable(chewable *chew.Chewable) error {
+	path := schemaPath
+	if declared, ok := chewable.Global["$schema"].(string); ok && path == "" && !strings.Contains(declared, "://") {
+		path = declared
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(dataPath), path)
+		}
+	}
+	if path == "" {
+		return nil
+	}
+
+	schema, err := chew.LoadSchema(path)
+	if err != nil {
+		return err
+	}
+	return schema.Validate(*chewable)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/lovromazgon/chew"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEntryTemplates(t *testing.T) {
+	defer func() { entryTemplates = nil }()
+
+	entryTemplates = []string{"tables:dao={{ .name }}.go", "db.views:view=", "csv={{ .name }}.csv"}
+	rules, templates, err := parseEntryTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, []chew.Rule{
+		{For: "tables", Template: "dao", Out: "{{ .name }}.go"},
+		{For: "db.views", Template: "view"},
+	}, rules)
+	assert.Equal(t, map[string]string{"csv": "{{ .name }}.csv"}, templates)
+
+	for _, invalid := range []string{"dao", "=out", ":dao=out", "tables:=out"} {
+		entryTemplates = []string{invalid}
+		_, _, err := parseEntryTemplates()
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestAddEntryTemplates(t *testing.T) {
+	shared := map[string]string{"row": "row.txt"}
+	chewable := &chew.Chewable{
+		Data: []chew.ChewableData{
+			{Templates: shared, Local: map[string]interface{}{"name": "a"}},
+			{Local: map[string]interface{}{"name": "b"}},
+		},
+	}
+
+	rules := []chew.Rule{{For: "tables", Template: "dao", Out: "{{ .name }}.go"}}
+	addEntryTemplates(chewable, rules, map[string]string{"csv": "{{ .name }}.csv"})
+	assert.Equal(t, rules, chewable.Rules)
+	assert.Equal(t, map[string]string{"row": "row.txt", "csv": "{{ .name }}.csv"}, chewable.Data[0].Templates)
+	assert.Equal(t, map[string]string{"csv": "{{ .name }}.csv"}, chewable.Data[1].Templates)
+	assert.Equal(t, map[string]string{"row": "row.txt"}, shared)
+}
+
+func TestTemplatesFlag(t *testing.T) {
+	defer func() { templatesPaths = nil }()
+
+	// paths containing commas are not split
+	err := templatesCmd.Flags().Parse([]string{"-t", "a,b/templates", "--templates", "pack.zip"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a,b/templates", "pack.zip"}, templatesPaths)
+}
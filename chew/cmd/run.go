@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringVarP(&configPath, "config", "c", DefaultConfigPath, "Path to the project file which defines the targets")
+	runCmd.Flags().BoolVar(&listTargets, "list", false, "List the targets instead of running them")
+	runCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Print skipped data objects and templates")
+
+	runCmd.MarkFlagFilename("config", "yaml", "yml")
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [target...]",
+	Short: "Run the targets defined in the project file (chew.yaml)",
+	Long: `The project file (chew.yaml) describes generation jobs as named targets, which define the
+templates, data, output folder, variables and all other options of the root command, so the
+same jobs can be run without long command lines. After generating, the commands defined in
+'post' are executed (e.g. to format the generated code). Without arguments all targets are
+run in alphabetical order.`,
+	RunE: runRun,
+}
+
+// ----------------------------------------------------------------
+
+var (
+	configPath  string
+	listTargets bool
+)
+
+func runRun(cmd *cobra.Command, args []string) error {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	if listTargets {
+		for _, name := range config.TargetNames() {
+			fmt.Println(name)
+		}
+		return nil
+	}
+
+	names := args
+	if len(names) == 0 {
+		names = config.TargetNames()
+	}
+	for _, name := range names {
+		if err := runTarget(config, name); err != nil {
+			return fmt.Errorf("Target '%s': %v", name, err)
+		}
+	}
+	return nil
+}
+
+// runTarget generates the output of the target and executes its post-processing commands.
+func runTarget(config *Config, name string) error {
+	target, err := config.Resolve(name)
+	if err != nil {
+		return err
+	}
+	if err := target.apply(); err != nil {
+		return err
+	}
+
+	if err := preChew(RootCmd, nil); err != nil {
+		return err
+	}
+	if err := chewRun(RootCmd, nil); err != nil {
+		return err
+	}
+
+	for _, command := range target.Post {
+		if len(command) == 0 {
+			continue
+		}
+		post := exec.Command(command[0], command[1:]...)
+		post.Dir = config.dir
+		post.Stdout = os.Stdout
+		post.Stderr = os.Stderr
+		if err := post.Run(); err != nil {
+			return fmt.Errorf("Could not run post-processing command '%s': %v", strings.Join(command, " "), err)
+		}
+	}
+	return nil
+}
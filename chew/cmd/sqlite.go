@@ -0,0 +1,15 @@
+//go:build cgo
+
+package cmd
+
+import (
+	"github.com/lovromazgon/chew"
+	"github.com/lovromazgon/chew/source/sqlite"
+)
+
+// registerSQLiteDecoder registers the decoder for SQLite databases, which requires cgo.
+func registerSQLiteDecoder() {
+	for _, ext := range sqliteExtensions {
+		chew.RegisterDecoder(ext, &sqlite.Decoder{})
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !cgo
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/lovromazgon/chew"
+)
+
+// registerSQLiteDecoder registers a decoder which reports that SQLite databases are not supported, because
+// the SQLite driver requires cgo.
+func registerSQLiteDecoder() {
+	for _, ext := range sqliteExtensions {
+		chew.RegisterDecoder(ext, chew.DecoderFunc(func(data []byte) (interface{}, error) {
+			return nil, errors.New("Could not read SQLite database, chew was built without cgo (CGO_ENABLED=0)")
+		}))
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lovromazgon/chew"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(templatesCmd)
+
+	templatesCmd.Flags().StringArrayVarP(&templatesPaths, "templates", "t", nil, "Path to folder (read recursively) or template pack (.zip, .tar.gz) with templates, can be repeated to override templates from earlier folders")
+}
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List all templates and the folder they were loaded from",
+	Long: `Chew parses the template folders in the order they are defined. A template in a later folder
+replaces the template with the same name from an earlier folder. This command lists the templates
+which will be used when generating, the folder each of them was loaded from and the
+description from the front matter of the template.`,
+	RunE: templatesRun,
+}
+
+// ----------------------------------------------------------------
+
+func templatesRun(cmd *cobra.Command, args []string) error {
+	if len(templatesPaths) == 0 {
+		return errors.New("Templates flag is required!")
+	} else if err := checkTemplatesPaths(); err != nil {
+		return err
+	}
+
+	template := chew.New("main")
+	_, err := template.ParseFolders(templatesPaths...)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range template.TemplateNames() {
+		description := ""
+		if meta := template.Meta(name); meta != nil {
+			description = meta.Description
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, template.Source(name), description)
+	}
+	return w.Flush()
+}
@@ -1,10 +1,14 @@
 package chew
 
 import (
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Chewable is the main object which carries the data in chew. It stores everything that is used
@@ -14,15 +18,31 @@ import (
 type Chewable struct {
 	Global map[string]interface{}
 	Data   []ChewableData
+	Rules  []Rule
 }
 
 // ChewableData is a collection of data which can be used in executing one or more templates.
 // The field Templates stores a map in which the key denotes the name of the template which will be generated
 // and the value denotes the output filename. Everything in field Local will be accessible in the templates.
 // If a key in the map Local also exists in the map Global in Chewable, the Local value will be used.
+// The optional field Matrix defines dimensions for which the ChewableData is expanded (see Matrix).
+//
+// The field When can contain a condition, a text/template boolean expression (e.g. `and .audit (eq .dialect "pg")`),
+// which is evaluated on the merged data. If the condition is not met the ChewableData is skipped. Conditions for
+// single templates are stored in the field Conditions, where the key is the name of the template. In JSON
+// a template condition is defined by using an object as the value in 'templates', both fields of the object
+// are optional (an empty 'out' uses the output pattern from the front matter of the template):
+//
+//	"templates": {
+//	  "table": "table.sql",
+//	  "audit": {"out": "audit.sql", "when": ".audit"}
+//	}
 type ChewableData struct {
-	Templates map[string]string
-	Local     map[string]interface{}
+	Templates  map[string]string
+	Local      map[string]interface{}
+	Matrix     Matrix
+	When       string
+	Conditions map[string]string
 }
 
 // UnmarshalJSON parses data from JSON into Chewable. Returns an error if parsing was unsuccessful, else nil.
@@ -33,31 +53,198 @@ func (c *Chewable) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	dataObj := global["data"]
-	if dataObj == nil {
+	return c.fromMap(global)
+}
+
+// MarshalJSON encodes Chewable into JSON. The fields of Global are stored at the top level next to
+// the fields 'data' and 'rules', so the result can be decoded with UnmarshalJSON again.
+func (c Chewable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ToDoc())
+}
+
+// UnmarshalYAML parses data from YAML into Chewable. The structure is the same as in JSON.
+func (c *Chewable) UnmarshalYAML(value *yaml.Node) error {
+	global := make(map[string]interface{})
+
+	if err := value.Decode(&global); err != nil {
+		return err
+	}
+
+	return c.fromMap(global)
+}
+
+// MarshalYAML returns the document form of Chewable, which is encoded into YAML the same way as in MarshalJSON.
+func (c Chewable) MarshalYAML() (interface{}, error) {
+	return c.ToDoc(), nil
+}
+
+// fromMap extracts the fields 'data' and 'rules' from global, everything else is stored in Chewable.Global.
+// At least one of the fields 'data' and 'rules' has to be defined.
+func (c *Chewable) fromMap(global map[string]interface{}) error {
+	dataObj, hasData := global["data"]
+	rulesObj, hasRules := global["rules"]
+	if !hasData && !hasRules {
 		return errors.New("Could not find field 'data'")
 	}
 
 	delete(global, "data")
+	delete(global, "rules")
 	c.Global = global
+	c.Data = nil
+	c.Rules = nil
 
-	dataSlice, ok := dataObj.([]interface{})
-	if !ok {
-		return errors.New("Field 'data' is not a slice")
+	if hasData {
+		dataSlice, ok := dataObj.([]interface{})
+		if !ok {
+			return errors.New("Field 'data' is not a slice")
+		}
+
+		c.Data = make([]ChewableData, len(dataSlice))
+		for i, d := range dataSlice {
+			cd, err := extractChewableData(d)
+			if err != nil {
+				return fmt.Errorf("Could not extract object %d in field 'data': %v", i, err)
+			}
+			c.Data[i] = cd
+		}
 	}
 
-	c.Data = make([]ChewableData, len(dataSlice))
-	for i, d := range dataSlice {
-		cd, err := extractChewableData(d)
-		if err != nil {
-			return fmt.Errorf("Could not extract object %d in field 'data': %v", i, err)
+	if hasRules {
+		rulesSlice, ok := rulesObj.([]interface{})
+		if !ok {
+			return errors.New("Field 'rules' is not a slice")
+		}
+
+		c.Rules = make([]Rule, len(rulesSlice))
+		for i, r := range rulesSlice {
+			rule, err := extractRule(r)
+			if err != nil {
+				return fmt.Errorf("Could not extract object %d in field 'rules': %v", i, err)
+			}
+			c.Rules[i] = rule
 		}
-		c.Data[i] = cd
 	}
 
 	return nil
 }
 
+// ToDoc is the inverse of UnmarshalJSON. It returns the document form of Chewable, where the fields of Global
+// are stored at the top level next to the fields 'data' and 'rules'. Decoders which create Chewable data
+// (e.g. from a database) return this form, so the result is parsed back into the same Chewable.
+func (c Chewable) ToDoc() map[string]interface{} {
+	doc := make(map[string]interface{}, len(c.Global)+2)
+	for k, v := range c.Global {
+		doc[k] = v
+	}
+
+	if len(c.Data) > 0 || len(c.Rules) == 0 {
+		data := make([]interface{}, len(c.Data))
+		for i, cd := range c.Data {
+			data[i] = cd.toMap()
+		}
+		doc["data"] = data
+	}
+
+	if len(c.Rules) > 0 {
+		rules := make([]interface{}, len(c.Rules))
+		for i, rule := range c.Rules {
+			rules[i] = rule.toMap()
+		}
+		doc["rules"] = rules
+	}
+
+	return doc
+}
+
+// toMap returns the document form of ChewableData, where the fields of Local are stored at the top level
+// next to the fields 'templates', 'matrix' and 'when'.
+func (cd ChewableData) toMap() map[string]interface{} {
+	doc := make(map[string]interface{}, len(cd.Local)+3)
+	for k, v := range cd.Local {
+		doc[k] = v
+	}
+
+	templates := make(map[string]interface{}, len(cd.Templates))
+	for tmpl, out := range cd.Templates {
+		if when, ok := cd.Conditions[tmpl]; ok {
+			templates[tmpl] = map[string]interface{}{
+				"out":  out,
+				"when": when,
+			}
+		} else {
+			templates[tmpl] = out
+		}
+	}
+	doc["templates"] = templates
+
+	if len(cd.Matrix) > 0 {
+		doc["matrix"] = map[string]interface{}(cd.Matrix)
+	}
+	if cd.When != "" {
+		doc["when"] = cd.When
+	}
+
+	return doc
+}
+
+// NewEntries returns a ChewableData for every element of items, which has to be a slice. The Local data of
+// each ChewableData contains the element converted with ToMap, the Templates are a copy of templates.
+func NewEntries(items interface{}, templates map[string]string) ([]ChewableData, error) {
+	itemsVal := reflect.ValueOf(items)
+	if itemsVal.Kind() != reflect.Slice && itemsVal.Kind() != reflect.Array {
+		return nil, fmt.Errorf("Could not create entries from type %T", items)
+	}
+
+	entries := make([]ChewableData, itemsVal.Len())
+	for i := range entries {
+		local, err := ToMap(itemsVal.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("Could not create entry %d: %v", i, err)
+		}
+		entryTemplates := make(map[string]string, len(templates))
+		for tmpl, out := range templates {
+			entryTemplates[tmpl] = out
+		}
+		entries[i] = ChewableData{
+			Templates: entryTemplates,
+			Local:     local,
+		}
+	}
+	return entries, nil
+}
+
+// Expand returns a copy of Chewable in which all rules are expanded into ChewableData and every
+// ChewableData with a matrix is replaced by the combinations of its dimensions. The returned Chewable
+// contains no rules and no matrices, the ChewableData created from rules is appended after the existing
+// ChewableData.
+func (c Chewable) Expand() (Chewable, error) {
+	expanded := Chewable{
+		Global: c.Global,
+	}
+
+	for i, cd := range c.Data {
+		if len(cd.Matrix) == 0 {
+			expanded.Data = append(expanded.Data, cd)
+			continue
+		}
+		data, err := cd.Matrix.expand(c, cd)
+		if err != nil {
+			return expanded, fmt.Errorf("Could not expand matrix of object %d in field 'data': %v", i, err)
+		}
+		expanded.Data = append(expanded.Data, data...)
+	}
+
+	for i, rule := range c.Rules {
+		data, err := rule.expand(c.Global)
+		if err != nil {
+			return expanded, fmt.Errorf("Could not expand rule %d: %v", i, err)
+		}
+		expanded.Data = append(expanded.Data, data...)
+	}
+
+	return expanded, nil
+}
+
 func extractChewableData(data interface{}) (cd ChewableData, err error) {
 	local, err := ToMap(data)
 	if err != nil {
@@ -75,14 +262,56 @@ func extractChewableData(data interface{}) (cd ChewableData, err error) {
 
 	templates := make(map[string]string)
 	for tmpl, outRaw := range templatesMap {
-		outString, ok := outRaw.(string)
+		if outString, ok := outRaw.(string); ok {
+			templates[tmpl] = outString
+			continue
+		}
+
+		outMap, ok := outRaw.(map[string]interface{})
 		if !ok {
-			return cd, fmt.Errorf("Value of %s in 'templates' is not a string", tmpl)
+			return cd, fmt.Errorf("Value of %s in 'templates' is not a string or object", tmpl)
+		}
+		outString := ""
+		if outRaw, ok := outMap["out"]; ok {
+			if outString, ok = outRaw.(string); !ok {
+				return cd, fmt.Errorf("Value of %s.out in 'templates' is not a string", tmpl)
+			}
 		}
 		templates[tmpl] = outString
+
+		whenRaw, ok := outMap["when"]
+		if !ok {
+			continue
+		}
+		when, ok := whenRaw.(string)
+		if !ok {
+			return cd, fmt.Errorf("Value of %s.when in 'templates' is not a string", tmpl)
+		}
+		if cd.Conditions == nil {
+			cd.Conditions = make(map[string]string)
+		}
+		cd.Conditions[tmpl] = when
+	}
+
+	if whenRaw, ok := local["when"]; ok {
+		when, ok := whenRaw.(string)
+		if !ok {
+			return cd, errors.New("Field 'when' is not a string")
+		}
+		cd.When = when
+	}
+
+	if matrixRaw, ok := local["matrix"]; ok {
+		matrix, err := ToMap(matrixRaw)
+		if err != nil {
+			return cd, fmt.Errorf("Field 'matrix' is not an object: %v", err)
+		}
+		cd.Matrix = matrix
 	}
 
 	delete(local, "templates")
+	delete(local, "matrix")
+	delete(local, "when")
 	cd.Local = local
 	cd.Templates = templates
 
@@ -90,32 +319,215 @@ func extractChewableData(data interface{}) (cd ChewableData, err error) {
 }
 
 // ToMap takes an object and extracts a map[string]interface{}. If the object is already a map[string]interface{}
-// it is returned as it is. If the object is a struct, then the fields of the struct are mapped to a map
-// where the keys are the names of the fields, while the values are the actual values. If anything else is
-// passed to the function an error is thrown.
+// it is returned as it is. If the object is a struct (or a pointer to a struct), then the fields of the struct
+// are mapped to a map where the keys are the names of the fields, while the values are the actual values.
+// Other maps are copied into a map[string]interface{}, non-string keys are formatted with fmt.Sprint.
+// If anything else is passed to the function an error is thrown.
+//
+// Struct fields are mapped like encoding/json does it:
+//   - the key is taken from the `chew` tag, then from the `json` tag, otherwise the field name is used
+//   - fields tagged with "-" and unexported fields are skipped
+//   - fields tagged with omitempty are skipped if they contain an empty value
+//   - fields of embedded structs without a tag are added to the parent map, unless the parent contains
+//     a field with the same key
+//
+// Nested structs, pointers, maps and slices of those are converted recursively. Nested values whose type
+// has exported methods (e.g. time.Time or a type implementing fmt.Stringer) are kept as they are, so
+// templates can still call their methods.
 func ToMap(data interface{}) (map[string]interface{}, error) {
-	var dataMap map[string]interface{}
-	var ok bool
-
-	if dataMap, ok = data.(map[string]interface{}); ok {
-		// nothing to do, this is already a map
-	} else {
-		dataVal := reflect.Indirect(reflect.ValueOf(data))
-		switch dataVal.Kind() {
-		case reflect.Struct:
-			dataMap = make(map[string]interface{})
-
-			for i := 0; i < dataVal.NumField(); i++ {
-				fieldName := dataVal.Type().Field(i).Name
-				fieldValue := dataVal.Field(i).Interface()
-				dataMap[fieldName] = fieldValue
+	if dataMap, ok := data.(map[string]interface{}); ok {
+		return dataMap, nil
+	}
+
+	dataVal := reflect.ValueOf(data)
+	for dataVal.Kind() == reflect.Ptr || dataVal.Kind() == reflect.Interface {
+		if dataVal.IsNil() {
+			return nil, fmt.Errorf("Could not extract map from nil %s", dataVal.Type())
+		}
+		dataVal = dataVal.Elem()
+	}
+
+	switch dataVal.Kind() {
+	case reflect.Struct:
+		dataMap := make(map[string]interface{})
+		structToMap(dataVal, dataMap)
+		return dataMap, nil
+	case reflect.Map:
+		return mapToMap(dataVal), nil
+	case reflect.Invalid:
+		return nil, errors.New("Could not extract map from nil")
+	}
+
+	return nil, fmt.Errorf("Could not extract map from type %s", dataVal.Type())
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// structToMap adds the fields of the struct to dataMap. Fields of embedded structs are added after
+// the other fields and don't override them.
+func structToMap(structVal reflect.Value, dataMap map[string]interface{}) {
+	structType := structVal.Type()
+	var embedded []reflect.Value
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			// unexported field
+			continue
+		}
+
+		name, omitEmpty, skip := fieldKey(field)
+		if skip {
+			continue
+		}
+
+		fieldVal := structVal.Field(i)
+		if field.Anonymous && name == "" {
+			embeddedVal := fieldVal
+			if embeddedVal.Kind() == reflect.Ptr {
+				if embeddedVal.IsNil() {
+					continue
+				}
+				embeddedVal = embeddedVal.Elem()
+			}
+			if embeddedVal.Kind() == reflect.Struct && !embeddedVal.Type().Implements(textMarshalerType) {
+				embedded = append(embedded, embeddedVal)
+				continue
 			}
+		}
 
-		default:
-			return nil, fmt.Errorf("Could not extract map from type %s", dataVal.Type())
+		if name == "" {
+			name = field.Name
+		}
+		if omitEmpty && isEmptyValue(fieldVal) {
+			continue
+		}
+		dataMap[name] = toMapValue(fieldVal)
+	}
+
+	for _, embeddedVal := range embedded {
+		embeddedMap := make(map[string]interface{})
+		structToMap(embeddedVal, embeddedMap)
+		for k, v := range embeddedMap {
+			if _, ok := dataMap[k]; !ok {
+				dataMap[k] = v
+			}
+		}
+	}
+}
+
+// fieldKey parses the `chew` or `json` tag of the field and returns the key (empty if not set),
+// whether omitempty is set and whether the field should be skipped.
+func fieldKey(field reflect.StructField) (string, bool, bool) {
+	tag, ok := field.Tag.Lookup("chew")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, options := tag, ""
+	if i := strings.Index(tag, ","); i >= 0 {
+		name, options = tag[:i], tag[i+1:]
+	}
+
+	omitEmpty := false
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
 
+// toMapValue converts structs and maps in the value to map[string]interface{}.
+func toMapValue(val reflect.Value) interface{} {
+	switch val.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			return nil
 		}
+		if val.Kind() == reflect.Ptr && hasMethods(val.Type()) {
+			return val.Interface()
+		}
+		return toMapValue(val.Elem())
+	case reflect.Struct:
+		if hasMethods(val.Type()) {
+			return val.Interface()
+		}
+		dataMap := make(map[string]interface{})
+		structToMap(val, dataMap)
+		return dataMap
+	case reflect.Map:
+		if val.IsNil() {
+			return nil
+		}
+		return mapToMap(val)
+	case reflect.Slice, reflect.Array:
+		if val.Kind() == reflect.Slice && val.IsNil() {
+			return nil
+		}
+		if hasMethods(val.Type().Elem()) {
+			return val.Interface()
+		}
+		switch val.Type().Elem().Kind() {
+		case reflect.Struct, reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Array:
+			list := make([]interface{}, val.Len())
+			for i := range list {
+				list[i] = toMapValue(val.Index(i))
+			}
+			return list
+		}
+		// slices of simple values (e.g. []string) are kept as they are
+		return val.Interface()
 	}
+	return val.Interface()
+}
 
-	return dataMap, nil
+// hasMethods returns true if the type or a pointer to the type has exported methods.
+func hasMethods(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Interface {
+		return false
+	}
+	if typ.Kind() != reflect.Ptr {
+		typ = reflect.PtrTo(typ)
+	}
+	return typ.NumMethod() > 0
+}
+
+func mapToMap(mapVal reflect.Value) map[string]interface{} {
+	dataMap := make(map[string]interface{}, mapVal.Len())
+	iter := mapVal.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+		var name string
+		if key.Kind() == reflect.String {
+			name = key.String()
+		} else {
+			name = fmt.Sprint(key.Interface())
+		}
+		dataMap[name] = toMapValue(iter.Value())
+	}
+	return dataMap
+}
+
+func isEmptyValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return val.Len() == 0
+	case reflect.Bool:
+		return !val.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return val.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return val.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return val.IsNil()
+	}
+	return false
 }
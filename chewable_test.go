@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestChewable_UnmarshalJSON(t *testing.T) {
@@ -37,3 +39,314 @@ func TestChewable_UnmarshalJSON(t *testing.T) {
 		},
 	}, chewable)
 }
+
+func TestChewable_Expand(t *testing.T) {
+	dataRaw, err := ioutil.ReadFile("test/data/test_rules.json")
+	assert.NoError(t, err)
+
+	chewable := &Chewable{}
+
+	err = json.Unmarshal(dataRaw, chewable)
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{For: "entities", Template: "entity", Out: "{{ .name }}.go"},
+		{For: "nested.codes", Template: "code"},
+	}, chewable.Rules)
+
+	expanded, err := chewable.Expand()
+	assert.NoError(t, err)
+	assert.Nil(t, expanded.Rules)
+	assert.Equal(t, []ChewableData{
+		{
+			Templates: map[string]string{"entity": "{{ .name }}.go"},
+			Local:     map[string]interface{}{"name": "users"},
+		},
+		{
+			Templates: map[string]string{"entity": "{{ .name }}.go"},
+			Local:     map[string]interface{}{"name": "orders"},
+		},
+		{
+			Templates: map[string]string{"code": ""},
+			Local:     map[string]interface{}{"code": "A"},
+		},
+	}, expanded.Data)
+
+	chewable.Rules = []Rule{{For: "missing", Template: "entity"}}
+	_, err = chewable.Expand()
+	assert.Error(t, err)
+}
+
+func TestChewable_Expand_Matrix(t *testing.T) {
+	dataRaw, err := ioutil.ReadFile("test/data/test_matrix.json")
+	assert.NoError(t, err)
+
+	chewable := &Chewable{}
+
+	err = json.Unmarshal(dataRaw, chewable)
+	assert.NoError(t, err)
+	assert.Equal(t, Matrix{
+		"entity":  "entities",
+		"dialect": []interface{}{"postgres", "mysql"},
+	}, chewable.Data[0].Matrix)
+
+	expanded, err := chewable.Expand()
+	assert.NoError(t, err)
+
+	templates := map[string]string{"dao": "{{ .dialect }}/{{ .entity.name }}.go"}
+	users := map[string]interface{}{"name": "users"}
+	orders := map[string]interface{}{"name": "orders"}
+	assert.Equal(t, []ChewableData{
+		{Templates: templates, Local: map[string]interface{}{"package": "dao", "dialect": "postgres", "entity": users}},
+		{Templates: templates, Local: map[string]interface{}{"package": "dao", "dialect": "postgres", "entity": orders}},
+		{Templates: templates, Local: map[string]interface{}{"package": "dao", "dialect": "mysql", "entity": users}},
+		{Templates: templates, Local: map[string]interface{}{"package": "dao", "dialect": "mysql", "entity": orders}},
+	}, expanded.Data)
+
+	chewable.Data[0].Matrix = Matrix{"dialect": "package"}
+	_, err = chewable.Expand()
+	assert.Error(t, err)
+}
+
+func TestChewable_UnmarshalJSON_When(t *testing.T) {
+	dataRaw, err := ioutil.ReadFile("test/data/test_when.json")
+	assert.NoError(t, err)
+
+	chewable := &Chewable{}
+
+	err = json.Unmarshal(dataRaw, chewable)
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, []ChewableData{
+		{
+			Templates: map[string]string{
+				"t1": "t1.out",
+				"t2": "t2.out",
+			},
+			Local: map[string]interface{}{
+				"skip": false,
+			},
+			When: "not .skip",
+			Conditions: map[string]string{
+				"t2": ".audit",
+			},
+		},
+	}, chewable.Data)
+}
+
+func TestChewable_UnmarshalJSON_TemplateObject(t *testing.T) {
+	chewable := &Chewable{}
+	err := json.Unmarshal([]byte(`{"data": [{"templates": {"t1": {"out": "t1.out"}, "t2": {}}}]}`), chewable)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"t1": "t1.out", "t2": ""}, chewable.Data[0].Templates)
+	assert.Nil(t, chewable.Data[0].Conditions)
+
+	err = json.Unmarshal([]byte(`{"data": [{"templates": {"t1": {"out": 1}}}]}`), chewable)
+	assert.EqualError(t, err, "Could not extract object 0 in field 'data': Value of t1.out in 'templates' is not a string")
+
+	err = json.Unmarshal([]byte(`{"data": [{"templates": {"t1": {"out": "t1.out", "when": true}}}]}`), chewable)
+	assert.EqualError(t, err, "Could not extract object 0 in field 'data': Value of t1.when in 'templates' is not a string")
+}
+
+type ToMapBase struct {
+	ID      int    `json:"id"`
+	Created string `json:"created"`
+}
+
+type toMapColumn struct {
+	Name     string `json:"name"`
+	Nullable bool   `json:"nullable,omitempty"`
+}
+
+type toMapTable struct {
+	ToMapBase
+	Name     string            `json:"name"`
+	Schema   string            `chew:"schema" json:"owner"`
+	Comment  string            `json:"comment,omitempty"`
+	Internal string            `json:"-"`
+	Columns  []toMapColumn     `json:"columns"`
+	Primary  *toMapColumn      `json:"primary"`
+	Parent   *toMapTable       `json:"parent"`
+	Indexes  map[int]string    `json:"indexes"`
+	Tags     []string          `json:"tags"`
+	Options  map[string]string `json:"options,omitempty"`
+	Created  string            `json:"created"`
+	Plain    string
+	private  string
+}
+
+func TestToMap(t *testing.T) {
+	table := &toMapTable{
+		ToMapBase: ToMapBase{ID: 1, Created: "base"},
+		Name:      "users",
+		Schema:    "app",
+		Internal:  "secret",
+		Columns:   []toMapColumn{{Name: "id"}, {Name: "email", Nullable: true}},
+		Primary:   &toMapColumn{Name: "id"},
+		Indexes:   map[int]string{1: "pk_users"},
+		Tags:      []string{"a", "b"},
+		Created:   "table",
+		Plain:     "plain",
+		private:   "private",
+	}
+
+	actual, err := ToMap(table)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":     1,
+		"name":   "users",
+		"schema": "app",
+		"columns": []interface{}{
+			map[string]interface{}{"name": "id"},
+			map[string]interface{}{"name": "email", "nullable": true},
+		},
+		"primary": map[string]interface{}{"name": "id"},
+		"parent":  nil,
+		"indexes": map[string]interface{}{"1": "pk_users"},
+		"tags":    []string{"a", "b"},
+		"created": "table",
+		"Plain":   "plain",
+	}, actual)
+}
+
+func TestToMap_Map(t *testing.T) {
+	data := map[string]interface{}{"a": 1}
+	actual, err := ToMap(data)
+	assert.Nil(t, err)
+	assert.Equal(t, data, actual)
+
+	actual, err = ToMap(map[int]toMapColumn{1: {Name: "id"}})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"1": map[string]interface{}{"name": "id"}}, actual)
+}
+
+func TestToMap_Error(t *testing.T) {
+	var table *toMapTable
+	_, err := ToMap(table)
+	assert.NotNil(t, err)
+
+	_, err = ToMap(nil)
+	assert.NotNil(t, err)
+
+	_, err = ToMap("string")
+	assert.NotNil(t, err)
+}
+
+func TestChewable_MarshalJSON(t *testing.T) {
+	for _, path := range []string{
+		"test/data/test_chewable.json",
+		"test/data/test_matrix.json",
+		"test/data/test_rules.json",
+		"test/data/test_when.json",
+	} {
+		dataRaw, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+
+		expected := Chewable{}
+		assert.NoError(t, json.Unmarshal(dataRaw, &expected))
+
+		marshalled, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		actual := Chewable{}
+		assert.NoError(t, json.Unmarshal(marshalled, &actual), path)
+		assert.Equal(t, expected, actual, path)
+	}
+}
+
+func TestChewable_MarshalYAML(t *testing.T) {
+	for _, path := range []string{
+		"test/data/test_matrix.json",
+		"test/data/test_rules.json",
+		"test/data/test_when.json",
+	} {
+		dataRaw, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+
+		expected := Chewable{}
+		assert.NoError(t, json.Unmarshal(dataRaw, &expected))
+
+		marshalled, err := yaml.Marshal(expected)
+		assert.NoError(t, err)
+
+		actual := Chewable{}
+		assert.NoError(t, yaml.Unmarshal(marshalled, &actual), path)
+		assert.Equal(t, expected, actual, path)
+	}
+}
+
+type toMapType struct {
+	Package string
+	Name    string
+}
+
+func (t toMapType) String() string {
+	return t.Package + "." + t.Name
+}
+
+func TestToMap_Methods(t *testing.T) {
+	created := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	data := struct {
+		Type    toMapType
+		Types   []toMapType
+		Created *time.Time
+		Column  toMapColumn
+	}{
+		Type:    toMapType{Package: "time", Name: "Time"},
+		Types:   []toMapType{{Package: "fmt", Name: "Stringer"}},
+		Created: &created,
+		Column:  toMapColumn{Name: "id"},
+	}
+
+	actual, err := ToMap(data)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"Type":    toMapType{Package: "time", Name: "Time"},
+		"Types":   []toMapType{{Package: "fmt", Name: "Stringer"}},
+		"Created": &created,
+		"Column":  map[string]interface{}{"name": "id"},
+	}, actual)
+}
+
+func TestNewEntries(t *testing.T) {
+	templates := map[string]string{"column": "{{ .name }}.go"}
+	entries, err := NewEntries([]toMapColumn{{Name: "id"}, {Name: "email", Nullable: true}}, templates)
+	assert.Nil(t, err)
+	assert.Equal(t, []ChewableData{
+		{Templates: map[string]string{"column": "{{ .name }}.go"}, Local: map[string]interface{}{"name": "id"}},
+		{Templates: map[string]string{"column": "{{ .name }}.go"}, Local: map[string]interface{}{"name": "email", "nullable": true}},
+	}, entries)
+
+	// every entry has its own copy of the templates
+	entries[0].Templates["other"] = "other.go"
+	assert.Equal(t, map[string]string{"column": "{{ .name }}.go"}, entries[1].Templates)
+	assert.Equal(t, map[string]string{"column": "{{ .name }}.go"}, templates)
+
+	_, err = NewEntries(toMapColumn{Name: "id"}, templates)
+	assert.NotNil(t, err)
+	_, err = NewEntries([]string{"id"}, templates)
+	assert.NotNil(t, err)
+}
+
+func TestChewable_ToDoc(t *testing.T) {
+	chewable := Chewable{
+		Global: map[string]interface{}{"package": "model"},
+		Data: []ChewableData{
+			{Templates: map[string]string{"column": "id.go"}, Local: map[string]interface{}{"name": "id"}},
+		},
+	}
+
+	doc := chewable.ToDoc()
+	assert.Equal(t, map[string]interface{}{
+		"package": "model",
+		"data": []interface{}{
+			map[string]interface{}{"name": "id", "templates": map[string]interface{}{"column": "id.go"}},
+		},
+	}, doc)
+
+	var decoded Chewable
+	assert.Nil(t, decoded.fromMap(doc))
+	assert.Equal(t, chewable, decoded)
+
+	// the field 'data' is always defined if there are no rules
+	assert.Equal(t, map[string]interface{}{"data": []interface{}{}}, Chewable{}.ToDoc())
+}
@@ -0,0 +1,102 @@
+package chew
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultTemplatesColumn is the name of the column which contains the templates of a row in CSV and TSV files.
+const DefaultTemplatesColumn = "templates"
+
+// CSVDecoder decodes CSV (or TSV) files, where each row becomes an object in the field 'data'. The first
+// row is the header, which contains the keys of the objects. Values are converted to booleans (true, false)
+// and numbers where possible, everything else (including numbers with leading zeros like 007) stays a string.
+//
+// The templates of a row are defined in the column TemplatesColumn as a list of template=out pairs separated
+// by semicolons, e.g. `code=codes/{{ .code }}.go;doc`. A template without an output uses the output defined
+// in its front matter. Templates can also be defined for all rows in the field Templates, templates in the
+// column override them.
+type CSVDecoder struct {
+	// Comma is the field delimiter, ',' for CSV and '\t' for TSV
+	Comma rune
+	// TemplatesColumn is the name of the column which contains the templates, DefaultTemplatesColumn is used if empty
+	TemplatesColumn string
+	// Templates contains the templates which are executed for every row
+	Templates map[string]string
+}
+
+// Decode decodes the CSV data into an object with the field 'data'.
+func (d *CSVDecoder) Decode(data []byte) (interface{}, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	if d.Comma != 0 {
+		reader.Comma = d.Comma
+	}
+	if reader.Comma == '\t' {
+		// TSV files usually don't quote fields
+		reader.LazyQuotes = true
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("Could not find header row")
+	}
+
+	templatesColumn := d.TemplatesColumn
+	if templatesColumn == "" {
+		templatesColumn = DefaultTemplatesColumn
+	}
+
+	header := records[0]
+	rows := make([]interface{}, 0, len(records)-1)
+	for i, record := range records[1:] {
+		row := make(map[string]interface{}, len(header)+1)
+		templates := make(map[string]interface{}, len(d.Templates))
+		for tmpl, out := range d.Templates {
+			templates[tmpl] = out
+		}
+
+		for j, key := range header {
+			if key == templatesColumn {
+				if err := parseCSVTemplates(record[j], templates); err != nil {
+					return nil, fmt.Errorf("Could not parse row %d: %v", i+1, err)
+				}
+				continue
+			}
+			row[key] = inferValue(record[j])
+		}
+
+		if len(templates) == 0 {
+			return nil, fmt.Errorf("Row %d has no templates, define them in column '%s'", i+1, templatesColumn)
+		}
+		row["templates"] = templates
+		rows = append(rows, row)
+	}
+
+	return map[string]interface{}{"data": rows}, nil
+}
+
+// parseCSVTemplates parses templates in the form `template=out;template` and adds them to templates.
+func parseCSVTemplates(value string, templates map[string]interface{}) error {
+	for _, mapping := range strings.Split(value, ";") {
+		mapping = strings.TrimSpace(mapping)
+		if mapping == "" {
+			continue
+		}
+
+		tmpl, out := mapping, ""
+		if i := strings.Index(mapping, "="); i >= 0 {
+			tmpl, out = strings.TrimSpace(mapping[:i]), strings.TrimSpace(mapping[i+1:])
+		}
+		if tmpl == "" {
+			return fmt.Errorf("Invalid template '%s', expected template=out", mapping)
+		}
+		templates[tmpl] = out
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package chew
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadChewable_CSV(t *testing.T) {
+	chewable, err := LoadChewable("test/data/csv/codes.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, []ChewableData{
+		{
+			Templates: map[string]string{"code": "codes/{{ .code }}.go"},
+			Local:     map[string]interface{}{"code": "007", "name": "Standard", "rate": 0.2, "active": true},
+		},
+		{
+			Templates: map[string]string{"code": "codes/{{ .code }}.go", "doc": ""},
+			Local:     map[string]interface{}{"code": "A1", "name": "Reduced, food", "rate": float64(5), "active": false},
+		},
+	}, chewable.Data)
+}
+
+func TestCSVDecoder_TSV(t *testing.T) {
+	decoder := &CSVDecoder{Comma: '\t', Templates: map[string]string{"code": "{{ .code }}.txt"}}
+	doc, err := decoder.Decode([]byte("code\tname\trate\nA\tFirst\t1\nB\tSecond \"quoted\"\t2.5\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{"code": "A", "name": "First", "rate": float64(1), "templates": map[string]interface{}{"code": "{{ .code }}.txt"}},
+			map[string]interface{}{"code": "B", "name": "Second \"quoted\"", "rate": 2.5, "templates": map[string]interface{}{"code": "{{ .code }}.txt"}},
+		},
+	}, doc)
+
+	_, err = LoadChewable("test/data/csv/codes.tsv")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Row 1 has no templates")
+}
@@ -0,0 +1,85 @@
+package chew
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var number = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// Decoder decodes the content of a data file into a generic tree of map[string]interface{}, []interface{}
+// and simple values, the same tree encoding/json produces when decoding into an interface{}.
+type Decoder interface {
+	Decode(data []byte) (interface{}, error)
+}
+
+// FileDecoder is a Decoder which reads the data file itself, e.g. because the file is a database. If the Decoder
+// registered for an extension implements FileDecoder, DecodeFile is called with the path of the file instead
+// of Decode.
+type FileDecoder interface {
+	Decoder
+	DecodeFile(path string) (interface{}, error)
+}
+
+// DecoderFunc is an adapter which allows using an ordinary function as a Decoder.
+type DecoderFunc func(data []byte) (interface{}, error)
+
+// Decode calls f(data).
+func (f DecoderFunc) Decode(data []byte) (interface{}, error) {
+	return f(data)
+}
+
+// decoders contains the registered decoders by file extension.
+var decoders = map[string]Decoder{
+	".json": DecoderFunc(decodeJSON),
+	".csv":  &CSVDecoder{Comma: ','},
+	".tsv":  &CSVDecoder{Comma: '\t'},
+	".xml":  &XMLDecoder{},
+	".yaml": &YAMLDecoder{},
+	".yml":  &YAMLDecoder{},
+}
+
+// RegisterDecoder registers the decoder for data files with the provided extension (e.g. ".csv"), replacing
+// a previously registered decoder. Registering a nil decoder removes the registration. Files with an extension
+// without a decoder are decoded as JSON.
+func RegisterDecoder(ext string, d Decoder) {
+	if d == nil {
+		delete(decoders, strings.ToLower(ext))
+		return
+	}
+	decoders[strings.ToLower(ext)] = d
+}
+
+// decoderFor returns the decoder for the file with the provided path.
+func decoderFor(path string) Decoder {
+	if d, ok := decoders[strings.ToLower(filepath.Ext(path))]; ok {
+		return d
+	}
+	return decoders[".json"]
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	var doc interface{}
+	err := json.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// inferValue converts the text from formats without types (e.g. CSV) to a boolean or a number (with the same
+// syntax as in JSON), otherwise the text is returned as it is.
+func inferValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if number.MatchString(value) {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
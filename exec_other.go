@@ -0,0 +1,9 @@
+//go:build !unix
+
+package chew
+
+import "os/exec"
+
+// killProcessGroup is not supported on this platform, only the command itself is killed when it is canceled.
+// Child processes which keep the output open are abandoned after execWaitDelay.
+func killProcessGroup(cmd *exec.Cmd) {}
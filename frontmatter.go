@@ -0,0 +1,144 @@
+package chew
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// frontMatterStart opens the front matter, an explicit marker so templates which generate YAML
+	// (starting with "---") are not mistaken for front matter
+	frontMatterStart = "---chew"
+	frontMatterEnd   = "---"
+
+	// OverwriteAlways truncates existing output files (default).
+	OverwriteAlways = "always"
+	// OverwriteNever keeps existing output files and skips the template.
+	OverwriteNever = "never"
+	// OverwriteError stops the execution if the output file already exists.
+	OverwriteError = "error"
+)
+
+// TemplateMeta contains the metadata of a template, defined in the front matter of the template file.
+// The front matter is an optional YAML or JSON block at the start of the file, which starts with a line
+// containing only "---chew" and ends with a line containing only "---":
+//
+//	---chew
+//	description: Creates a table
+//	required:
+//	  name: string
+//	  columns: array
+//	defaults:
+//	  schema: public
+//	output: "{{ .name }}.sql"
+//	overwrite: never
+//	---
+//	CREATE TABLE {{ .schema }}.{{ .name }} ...
+//
+// Supported types of required fields are string, number, bool, array, object and any. Unknown keys in the
+// front matter are reported as errors. Templates starting with a plain "---" line (e.g. YAML documents)
+// have no front matter.
+type TemplateMeta struct {
+	Description string                 `yaml:"description" json:"description"`
+	Required    map[string]string      `yaml:"required" json:"required"`
+	Defaults    map[string]interface{} `yaml:"defaults" json:"defaults"`
+	// Output is the pattern for the output filename used when the template mapping doesn't define one.
+	Output string `yaml:"output" json:"output"`
+	// Overwrite is the policy for existing output files, one of OverwriteAlways, OverwriteNever or OverwriteError.
+	Overwrite string `yaml:"overwrite" json:"overwrite"`
+}
+
+// parseFrontMatter splits the content of a template file into the metadata and the template itself.
+// If the content doesn't start with a front matter, nil metadata and the unchanged content are returned.
+func parseFrontMatter(content []byte) (*TemplateMeta, []byte, error) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if len(lines) == 0 || string(bytes.TrimSpace(lines[0])) != frontMatterStart {
+		return nil, content, nil
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if string(bytes.TrimSpace(lines[i])) != frontMatterEnd {
+			continue
+		}
+
+		meta := &TemplateMeta{}
+		decoder := yaml.NewDecoder(bytes.NewReader(bytes.Join(lines[1:i], nil)))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(meta); err != nil && err != io.EOF {
+			return nil, nil, fmt.Errorf("Could not parse front matter: %v", err)
+		}
+		if err := meta.check(); err != nil {
+			return nil, nil, err
+		}
+		return meta, bytes.Join(lines[i+1:], nil), nil
+	}
+
+	return nil, nil, errors.New("Front matter is not closed")
+}
+
+func (meta *TemplateMeta) check() error {
+	switch meta.Overwrite {
+	case "", OverwriteAlways, OverwriteNever, OverwriteError:
+	default:
+		return fmt.Errorf("Unknown overwrite policy '%s'", meta.Overwrite)
+	}
+
+	for field, typ := range meta.Required {
+		switch typ {
+		case "string", "number", "bool", "array", "object", "any":
+		default:
+			return fmt.Errorf("Unknown type '%s' of required field '%s'", typ, field)
+		}
+	}
+	return nil
+}
+
+// apply adds the default values to data for all keys which are not defined in data and checks
+// if all required fields exist and are of the correct type.
+func (meta *TemplateMeta) apply(data map[string]interface{}) error {
+	for k, v := range meta.Defaults {
+		if _, ok := data[k]; !ok {
+			data[k] = v
+		}
+	}
+
+	for field, typ := range meta.Required {
+		v, ok := data[field]
+		if !ok {
+			return fmt.Errorf("Missing required field '%s'", field)
+		}
+		if !isType(v, typ) {
+			return fmt.Errorf("Field '%s' is not of type %s", field, typ)
+		}
+	}
+	return nil
+}
+
+func isType(v interface{}, typ string) bool {
+	if typ == "any" {
+		return true
+	} else if v == nil {
+		return false
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String:
+		return typ == "string"
+	case reflect.Bool:
+		return typ == "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return typ == "number"
+	case reflect.Slice, reflect.Array:
+		return typ == "array"
+	case reflect.Map, reflect.Struct:
+		return typ == "object"
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package chew
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	testCases := []struct {
+		Content  string
+		Meta     *TemplateMeta
+		Template string
+		Error    bool
+	}{
+		{"no front matter", nil, "no front matter", false},
+		{"---chew\ndescription: test\n---\ncontent", &TemplateMeta{Description: "test"}, "content", false},
+		{"---chew\r\n{\"output\": \"out\"}\r\n---\r\ncontent", &TemplateMeta{Output: "out"}, "content", false},
+		{"---chew\n---\ncontent", &TemplateMeta{}, "content", false},
+		{"---\nname: {{ .name }}\n---\nkind: table\n", nil, "---\nname: {{ .name }}\n---\nkind: table\n", false},
+		{"---\nname: {{ .name }}\n", nil, "---\nname: {{ .name }}\n", false},
+		{"---chew\ndescription: test\ncontent", nil, "", true},
+		{"---chew\noverwrite: sometimes\n---\n", nil, "", true},
+		{"---chew\nrequired:\n  name: text\n---\n", nil, "", true},
+		{"---chew\nname: users\n---\n", nil, "", true},
+	}
+
+	for _, tc := range testCases {
+		meta, content, err := parseFrontMatter([]byte(tc.Content))
+		if tc.Error {
+			assert.Error(t, err, tc.Content)
+			continue
+		}
+		assert.NoError(t, err, tc.Content)
+		assert.Equal(t, tc.Meta, meta, tc.Content)
+		assert.Equal(t, tc.Template, string(content), tc.Content)
+	}
+}
+
+func TestTemplate_ExecuteChewable_FrontMatter(t *testing.T) {
+	template := New("main")
+	_, err := template.ParseFolder("test/frontmatter")
+	assert.NoError(t, err)
+	assert.Equal(t, "Creates a table", template.Meta("table").Description)
+
+	dir, err := ioutil.TempDir("", "chew")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	chewable := Chewable{
+		Data: []ChewableData{{
+			Templates: map[string]string{"table": ""},
+			Local: map[string]interface{}{
+				"name":    "users",
+				"columns": []interface{}{"id", "name"},
+			},
+		}},
+	}
+
+	err = template.ExecuteChewable(&MultiFileWriter{Out: dir}, chewable)
+	assert.NoError(t, err)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "users.sql"))
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE public.users (id, name);", string(content))
+
+	// overwrite policy "never" keeps the existing file
+	chewable.Data[0].Local["columns"] = []interface{}{"id"}
+	err = template.ExecuteChewable(&MultiFileWriter{Out: dir}, chewable)
+	assert.NoError(t, err)
+	content, err = ioutil.ReadFile(filepath.Join(dir, "users.sql"))
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE public.users (id, name);", string(content))
+
+	// missing required field
+	delete(chewable.Data[0].Local, "columns")
+	err = template.ExecuteChewable(WriterWrapper{new(bytes.Buffer)}, chewable)
+	assert.Error(t, err)
+
+	// wrong type of required field
+	chewable.Data[0].Local["columns"] = "id"
+	err = template.ExecuteChewable(WriterWrapper{new(bytes.Buffer)}, chewable)
+	assert.Error(t, err)
+
+	// overwrite policy "error" fails when the file exists
+	chewable = Chewable{
+		Data: []ChewableData{{
+			Templates: map[string]string{"json": "{{ .name }}.txt"},
+			Local:     map[string]interface{}{"name": "users"},
+		}},
+	}
+	err = template.ExecuteChewable(&MultiFileWriter{Out: dir}, chewable)
+	assert.NoError(t, err)
+	err = template.ExecuteChewable(&MultiFileWriter{Out: dir}, chewable)
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package chew
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// execution returns a copy of the Template for a single execution of the expanded Chewable. The copy
+// contains a clone of the parsed templates in which the functions are bound to the copy, so the index
+// used by the functions ref and entries isn't shared between concurrent executions.
+func (ct *Template) execution(c Chewable) (*Template, error) {
+	clone, err := ct.Template.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	exec := &Template{
+		Template:     clone,
+		Functions:    ct.Functions,
+		Sources:      ct.Sources,
+		Packs:        ct.Packs,
+		Verbose:      ct.Verbose,
+		IDField:      ct.IDField,
+		EnvAllowlist: ct.EnvAllowlist,
+		meta:         ct.meta,
+		layouts:      ct.layouts,
+	}
+	exec.injectFuncsOnce.Do(func() {})
+
+	exec.funcs = ct.Functions.FuncMap()
+	exec.funcs["indentTemplate"] = exec.IndentTemplate
+	exec.funcs["indentTemplates"] = exec.IndentTemplates
+	exec.funcs["plugins"] = exec.Plugins
+	exec.funcs["ref"] = exec.Ref
+	exec.funcs["entries"] = exec.Entries
+	clone.Funcs(exec.funcs)
+
+	exec.indexChewable(c)
+	return exec, nil
+}
+
+// indexChewable stores the data of every ChewableData in Chewable, so it can be accessed by the functions
+// ref and entries. ChewableData is indexed by the value of the field IDField in Local, ChewableData without
+// this field can't be referenced. Duplicate ids are only reported if they are referenced with ref.
+func (ct *Template) indexChewable(c Chewable) {
+	ct.entries = make([]interface{}, len(c.Data))
+	ct.index = make(map[string][]int)
+
+	for i, cd := range c.Data {
+		ct.entries[i] = prepareData(c, cd)
+
+		idRaw, ok := cd.Local[ct.IDField]
+		if !ok || ct.IDField == "" {
+			continue
+		}
+		id := fmt.Sprint(idRaw)
+		ct.index[id] = append(ct.index[id], i)
+	}
+}
+
+// Ref returns the data of the ChewableData with the provided id (see Template.IDField). The data contains
+// the fields from Chewable.Global and ChewableData.Local. Ref can only be used while executing a Chewable.
+func (ct *Template) Ref(id interface{}) (map[string]interface{}, error) {
+	if ct.index == nil {
+		return nil, errors.New("ref can only be used when executing a Chewable")
+	}
+
+	objects := ct.index[fmt.Sprint(id)]
+	switch len(objects) {
+	case 0:
+		return nil, fmt.Errorf("Could not find object with %s '%v'", ct.IDField, id)
+	case 1:
+		return ct.entries[objects[0]].(map[string]interface{}), nil
+	}
+
+	positions := make([]string, len(objects))
+	for i, object := range objects {
+		positions[i] = fmt.Sprint(object)
+	}
+	return nil, fmt.Errorf("Duplicate %s '%v' in objects %s in field 'data'", ct.IDField, id, strings.Join(positions, ", "))
+}
+
+// Entries returns the data of all ChewableData in the executed Chewable. The data of each ChewableData
+// contains the fields from Chewable.Global and ChewableData.Local. Entries can only be used while executing
+// a Chewable.
+func (ct *Template) Entries() ([]interface{}, error) {
+	if ct.entries == nil {
+		return nil, errors.New("entries can only be used when executing a Chewable")
+	}
+	return ct.entries, nil
+}
@@ -0,0 +1,122 @@
+package chew
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate_Ref(t *testing.T) {
+	template := New("main")
+	_, err := template.Parse(`
+{{- define "test_ref.tmpl" }}{{ .name }} -> {{ (ref .parent_table).name }} ({{ (ref .parent_table).schema }}){{ end }}
+{{- define "test_entries.tmpl" }}{{ range entries }}{{ .name }};{{ end }}{{ end }}`)
+	assert.NoError(t, err)
+
+	chewable := Chewable{
+		Global: map[string]interface{}{"schema": "public"},
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"test_ref": "lines.out"},
+				Local:     map[string]interface{}{"id": "order_lines", "name": "Order lines", "parent_table": "orders"},
+			},
+			{
+				Templates: map[string]string{"test_entries": "orders.out"},
+				Local:     map[string]interface{}{"id": "orders", "name": "Orders"},
+			},
+		},
+	}
+
+	buffer := new(bytes.Buffer)
+	err = template.ExecuteChewable(WriterWrapper{buffer}, chewable)
+	assert.NoError(t, err)
+	assert.Equal(t, "Order lines -> Orders (public)Order lines;Orders;", buffer.String())
+
+	chewable.Data[0].Local["parent_table"] = "missing"
+	err = template.ExecuteChewable(WriterWrapper{new(bytes.Buffer)}, chewable)
+	assert.Error(t, err)
+
+	chewable.Data[0].Local["parent_table"] = "orders"
+	chewable.Data[0].Local["id"] = "orders"
+	err = template.ExecuteChewable(WriterWrapper{new(bytes.Buffer)}, chewable)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Duplicate id 'orders' in objects 0, 1 in field 'data'")
+}
+
+func TestTemplate_Ref_DuplicateNotReferenced(t *testing.T) {
+	template := New("main")
+	_, err := template.Parse(`{{ define "test_dao.tmpl" }}{{ .id }}/{{ .dialect }};{{ end }}`)
+	assert.NoError(t, err)
+
+	// every combination of the matrix has the same id, which is fine as long as ref isn't used
+	buffer := new(bytes.Buffer)
+	err = template.ExecuteChewable(WriterWrapper{buffer}, Chewable{
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"test_dao": "out"},
+				Local:     map[string]interface{}{"id": "dao"},
+				Matrix:    Matrix{"dialect": []interface{}{"postgres", "mysql"}},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "dao/postgres;dao/mysql;", buffer.String())
+}
+
+func TestTemplate_Ref_Concurrent(t *testing.T) {
+	template := New("main")
+	_, err := template.Parse(`{{ define "test_ref.tmpl" }}{{ (ref "parent").name }}{{ end }}`)
+	assert.NoError(t, err)
+
+	chewable := func(name string) Chewable {
+		return Chewable{
+			Data: []ChewableData{
+				{Templates: map[string]string{"test_ref": "out"}, Local: map[string]interface{}{"id": "child"}},
+				{Templates: map[string]string{}, Local: map[string]interface{}{"id": "parent", "name": name}},
+			},
+		}
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			buffer := new(bytes.Buffer)
+			if err := template.ExecuteChewable(WriterWrapper{buffer}, chewable(fmt.Sprint(i))); err == nil {
+				results[i] = buffer.String()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		assert.Equal(t, fmt.Sprint(i), result)
+	}
+
+	_, err = template.Ref("parent")
+	assert.EqualError(t, err, "ref can only be used when executing a Chewable")
+}
+
+func TestTemplate_Ref_IDField(t *testing.T) {
+	template := New("main")
+	template.IDField = "table"
+	_, err := template.Parse(`{{ define "test_ref.tmpl" }}{{ (ref "orders").table }}{{ end }}`)
+	assert.NoError(t, err)
+
+	buffer := new(bytes.Buffer)
+	err = template.ExecuteChewable(WriterWrapper{buffer}, Chewable{
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"test_ref": "out"},
+				Local:     map[string]interface{}{"table": "orders"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", buffer.String())
+}
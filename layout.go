@@ -0,0 +1,105 @@
+package chew
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// extendsDirective matches the directive with which a template declares its parent layout. It has to
+// be the first thing in the template file, e.g.:
+//
+//	{{/* extends "base" */}}
+var extendsDirective = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*extends\s+"([^"]+)"\s*\*/\s*-?\}\}`)
+
+// layout stores a template which extends a parent layout. Such templates are not parsed into the
+// shared set of templates, so that the blocks they override don't leak into other templates.
+type layout struct {
+	parent  string
+	content string
+}
+
+// parseExtends returns the name of the parent layout (with the template suffix) if the template
+// content starts with the extends directive, else an empty string.
+func parseExtends(content []byte) string {
+	match := extendsDirective.FindSubmatch(content)
+	if match == nil {
+		return ""
+	}
+	return string(match[1]) + templateSuffix
+}
+
+// addLayout remembers a template which extends a parent layout. The template is parsed into a
+// throwaway template to report syntax errors early.
+func (ct *Template) addLayout(name, parent string, content []byte) error {
+	if _, err := template.New(name).Funcs(ct.Functions.FuncMap()).Parse(string(content)); err != nil {
+		return err
+	}
+
+	if ct.layouts == nil {
+		ct.layouts = make(map[string]layout)
+	}
+	ct.layouts[name] = layout{
+		parent:  parent,
+		content: string(content),
+	}
+	return nil
+}
+
+// lookup returns the template with the provided name (with the template suffix). If the template
+// extends a layout, a clone of all templates is created in which the blocks of the layouts and
+// finally the template itself are parsed, so the blocks override the ones defined in their parents.
+// The root layout of the clone is returned. Resolved templates are cached until the next parse.
+func (ct *Template) lookup(name string) (*template.Template, error) {
+	if _, ok := ct.layouts[name]; !ok {
+		tmpl := ct.Lookup(name)
+		if tmpl == nil {
+			return nil, fmt.Errorf("Could not find template '%s'", name)
+		}
+		return tmpl, nil
+	}
+
+	ct.resolvedMutex.Lock()
+	defer ct.resolvedMutex.Unlock()
+
+	if tmpl, ok := ct.resolved[name]; ok {
+		return tmpl, nil
+	}
+
+	chain := []string{name}
+	root := ct.layouts[name].parent
+	for {
+		l, ok := ct.layouts[root]
+		if !ok {
+			break
+		}
+		for _, c := range chain {
+			if c == root {
+				return nil, fmt.Errorf("Layout cycle detected: %s -> %s", strings.Join(chain, " -> "), root)
+			}
+		}
+		chain = append(chain, root)
+		root = l.parent
+	}
+
+	if ct.Lookup(root) == nil {
+		return nil, fmt.Errorf("Could not find layout '%s' extended by '%s'", root, chain[len(chain)-1])
+	}
+
+	clone, err := ct.Template.Clone()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if _, err := clone.New(chain[i]).Parse(ct.layouts[chain[i]].content); err != nil {
+			return nil, err
+		}
+	}
+
+	if ct.resolved == nil {
+		ct.resolved = make(map[string]*template.Template)
+	}
+	ct.resolved[name] = clone.Lookup(root)
+	return ct.resolved[name], nil
+}
@@ -0,0 +1,49 @@
+package chew
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate_Layout(t *testing.T) {
+	template := New("main")
+	_, err := template.ParseFolder("test/layouts")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		Template string
+		Name     string
+		Expected string
+	}{
+		{"page_a", "A", "Header of A\nBody of A\nDefault footer for A"},
+		{"page_b", "B", "Default header\nDefault body\nFooter of B"},
+		{"page_c", "C", "Default header\nSection: Content of C\nDefault footer for C"},
+		{"layout_section", "S", "Default header\nSection: Default content\nDefault footer for S"},
+		{"layout_base", "L", "Default header\nDefault body\nDefault footer for L"},
+	}
+
+	for _, tc := range testCases {
+		buffer := new(bytes.Buffer)
+		err := template.ExecuteChewable(WriterWrapper{buffer}, Chewable{
+			Data: []ChewableData{{
+				Templates: map[string]string{tc.Template: "out"},
+				Local:     map[string]interface{}{"name": tc.Name},
+			}},
+		})
+		assert.NoError(t, err, tc.Template)
+		assert.Equal(t, tc.Expected, buffer.String(), tc.Template)
+	}
+}
+
+func TestTemplate_Layout_Cycle(t *testing.T) {
+	template := New("main")
+	_, err := template.ParseFolder("test/layouts")
+	assert.NoError(t, err)
+
+	err = template.ExecuteChewable(WriterWrapper{new(bytes.Buffer)}, Chewable{
+		Data: []ChewableData{{Templates: map[string]string{"page_d": "out"}}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,414 @@
+package chew
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	includeKey = "$include"
+	refKey     = "$ref"
+	execKey    = "$exec"
+	formatKey  = "$format"
+)
+
+// DefaultExecTimeout is the maximum duration of a command executed with $exec if LoadOptions.ExecTimeout is not set.
+const DefaultExecTimeout = 30 * time.Second
+
+// LoadOptions define how data files are loaded by LoadChewableWithOptions.
+type LoadOptions struct {
+	// Exec enables objects with the field $exec, which execute commands. It is disabled by default, because
+	// loading a data file shouldn't execute code unless this is explicitly allowed.
+	Exec bool
+	// ExecTimeout is the maximum duration of a command, DefaultExecTimeout is used if zero
+	ExecTimeout time.Duration
+	// ExecDir is the working directory of commands, the folder of the file which contains $exec is used if empty
+	ExecDir string
+	// Format is the extension (e.g. "yaml") of the Decoder used for the data file instead of the Decoder
+	// registered for its own extension, included files are still decoded based on their extension. An error
+	// is returned if no Decoder is registered for the format.
+	Format string
+}
+
+// LoadChewable reads the data file with the provided path and parses it into a Chewable. The file is decoded
+// with the Decoder registered for its extension (see RegisterDecoder), JSON is used by default. While decoding,
+// the following special objects are replaced:
+//   - {"$include": "path.json"} is replaced by the content of the included file. If the included content
+//     is an object, other fields next to $include are added to it and override its fields.
+//   - {"$ref": "#/definitions/entity"} is replaced by the value referenced by the JSON pointer in the same
+//     file. A reference can also point into another file, e.g. {"$ref": "common.json#/columns/id"}.
+//
+// Relative paths are resolved relative to the file which contains them. Cyclic includes and references
+// are reported as errors, which contain the chain of included files.
+func LoadChewable(path string) (*Chewable, error) {
+	return LoadChewableWithOptions(path, LoadOptions{})
+}
+
+// LoadChewableWithOptions reads the data file like LoadChewable. If commands are enabled in opts, the
+// following special object is also replaced:
+//   - {"$exec": ["./list-tables.sh", "--json"]} is replaced by the output of the command, which is decoded
+//     with the Decoder registered for the extension in the field $format (e.g. {"$format": "csv"}), JSON is
+//     used by default. The output is not resolved further. If it is an object, other fields next to $exec
+//     are added to it and override its fields.
+//
+// The command is executed directly, not in a shell. A relative command path is resolved relative to the
+// working directory. A command which fails or exceeds the timeout is reported as error with its stderr.
+func LoadChewableWithOptions(path string, opts LoadOptions) (*Chewable, error) {
+	l := &loader{
+		files: make(map[string]interface{}),
+		opts:  opts,
+	}
+
+	if opts.Format != "" {
+		ext := "." + strings.ToLower(strings.TrimPrefix(opts.Format, "."))
+		decoder, ok := decoders[ext]
+		if !ok {
+			return nil, fmt.Errorf("Could not find a decoder for the format '%s'", opts.Format)
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := decodeFileWith(decoder, absPath)
+		if err != nil {
+			return nil, err
+		}
+		l.files[absPath] = doc
+	}
+
+	tree, err := l.include(path)
+	if err != nil {
+		return nil, err
+	}
+
+	global, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Data in %s is not an object", path)
+	}
+
+	chewable := &Chewable{}
+	if err := chewable.fromMap(global); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return chewable, nil
+}
+
+// loader resolves includes and references while loading data files.
+type loader struct {
+	// files caches decoded (unresolved) files by their absolute path
+	files map[string]interface{}
+	// chain contains the files which are currently being included
+	chain []string
+	// refs contains the references which are currently being resolved
+	refs []string
+	opts LoadOptions
+}
+
+// include reads, decodes and resolves the file with the provided path.
+func (l *loader) include(path string) (interface{}, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, included := range l.chain {
+		if included == absPath {
+			return nil, l.errorf("Include cycle detected: %s", path)
+		}
+	}
+
+	l.chain = append(l.chain, absPath)
+	defer func() { l.chain = l.chain[:len(l.chain)-1] }()
+
+	doc, err := l.read(absPath)
+	if err != nil {
+		return nil, l.errorf("%v", err)
+	}
+
+	return l.resolve(doc, doc)
+}
+
+// read decodes the file with the provided absolute path without resolving it.
+func (l *loader) read(absPath string) (interface{}, error) {
+	if doc, ok := l.files[absPath]; ok {
+		return doc, nil
+	}
+
+	doc, err := decodeFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	l.files[absPath] = doc
+	return doc, nil
+}
+
+// decodeFile decodes the file with the Decoder registered for its extension.
+func decodeFile(path string) (interface{}, error) {
+	return decodeFileWith(decoderFor(path), path)
+}
+
+// decodeFileWith decodes the file with the provided Decoder.
+func decodeFileWith(decoder Decoder, path string) (interface{}, error) {
+	if fileDecoder, ok := decoder.(FileDecoder); ok {
+		doc, err := fileDecoder.DecodeFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Could not decode %s: %v", path, err)
+		}
+		return doc, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := decoder.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode %s: %v", path, err)
+	}
+	return doc, nil
+}
+
+// resolve returns a copy of node in which all includes and references are replaced. References without
+// a file are resolved in doc, the document of the file currently being resolved.
+func (l *loader) resolve(node interface{}, doc interface{}) (interface{}, error) {
+	switch val := node.(type) {
+	case map[string]interface{}:
+		if _, ok := val[includeKey]; ok {
+			return l.resolveInclude(val, doc)
+		} else if _, ok := val[refKey]; ok {
+			return l.resolveRef(val, doc)
+		} else if _, ok := val[execKey]; ok {
+			return l.resolveExec(val, doc)
+		}
+
+		resolved := make(map[string]interface{}, len(val))
+		for _, k := range sortedKeys(val) {
+			r, err := l.resolve(val[k], doc)
+			if err != nil {
+				return nil, err
+			}
+			resolved[k] = r
+		}
+		return resolved, nil
+
+	case []interface{}:
+		resolved := make([]interface{}, len(val))
+		for i, v := range val {
+			r, err := l.resolve(v, doc)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	}
+
+	return node, nil
+}
+
+func (l *loader) resolveInclude(node map[string]interface{}, doc interface{}) (interface{}, error) {
+	path, ok := node[includeKey].(string)
+	if !ok {
+		return nil, l.errorf("Field '%s' is not a string", includeKey)
+	}
+
+	included, err := l.include(l.relative(path))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(node) == 1 {
+		return included, nil
+	}
+
+	includedMap, ok := included.(map[string]interface{})
+	if !ok {
+		return nil, l.errorf("Included file %s is not an object and can't be extended with other fields", path)
+	}
+
+	for _, k := range sortedKeys(node) {
+		if k == includeKey {
+			continue
+		}
+		v, err := l.resolve(node[k], doc)
+		if err != nil {
+			return nil, err
+		}
+		includedMap[k] = v
+	}
+	return includedMap, nil
+}
+
+func (l *loader) resolveRef(node map[string]interface{}, doc interface{}) (interface{}, error) {
+	ref, ok := node[refKey].(string)
+	if !ok {
+		return nil, l.errorf("Field '%s' is not a string", refKey)
+	}
+
+	file, pointer := ref, ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		file, pointer = ref[:i], ref[i+1:]
+	}
+
+	current := l.chain[len(l.chain)-1]
+	if file != "" {
+		absPath, err := filepath.Abs(l.relative(file))
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = l.read(absPath); err != nil {
+			return nil, l.errorf("Could not resolve reference '%s': %v", ref, err)
+		}
+		current = absPath
+	}
+
+	key := current + "#" + pointer
+	for _, r := range l.refs {
+		if r == key {
+			return nil, l.errorf("Reference cycle detected: %s", ref)
+		}
+	}
+
+	target, err := ResolvePointer(doc, pointer)
+	if err != nil {
+		return nil, l.errorf("Could not resolve reference '%s': %v", ref, err)
+	}
+
+	l.refs = append(l.refs, key)
+	defer func() { l.refs = l.refs[:len(l.refs)-1] }()
+
+	if file != "" {
+		// references in the other file are resolved relative to that file
+		l.chain = append(l.chain, current)
+		defer func() { l.chain = l.chain[:len(l.chain)-1] }()
+	}
+	return l.resolve(target, doc)
+}
+
+func (l *loader) resolveExec(node map[string]interface{}, doc interface{}) (interface{}, error) {
+	if !l.opts.Exec {
+		return nil, l.errorf("Field '%s' executes a command, which is disabled", execKey)
+	}
+
+	args, ok := node[execKey].([]interface{})
+	if !ok || len(args) == 0 {
+		return nil, l.errorf("Field '%s' is not a non-empty list of strings", execKey)
+	}
+	command := make([]string, len(args))
+	for i, arg := range args {
+		if command[i], ok = arg.(string); !ok {
+			return nil, l.errorf("Field '%s' is not a non-empty list of strings", execKey)
+		}
+	}
+
+	format := "json"
+	if f, ok := node[formatKey]; ok {
+		if format, ok = f.(string); !ok {
+			return nil, l.errorf("Field '%s' is not a string", formatKey)
+		}
+	}
+
+	output, err := l.exec(command)
+	if err != nil {
+		return nil, l.errorf("Could not execute command '%s': %v", strings.Join(command, " "), err)
+	}
+	result, err := decoderFor("output." + format).Decode(output)
+	if err != nil {
+		return nil, l.errorf("Could not decode output of command '%s': %v", strings.Join(command, " "), err)
+	}
+
+	fields := len(node) - 1
+	if _, ok := node[formatKey]; ok {
+		fields--
+	}
+	if fields == 0 {
+		return result, nil
+	}
+
+	resultMap, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, l.errorf("Output of command '%s' is not an object and can't be extended with other fields", strings.Join(command, " "))
+	}
+
+	for _, k := range sortedKeys(node) {
+		if k == execKey || k == formatKey {
+			continue
+		}
+		v, err := l.resolve(node[k], doc)
+		if err != nil {
+			return nil, err
+		}
+		resultMap[k] = v
+	}
+	return resultMap, nil
+}
+
+// execWaitDelay is the time to wait for the output of a command to be closed after the command exits or is
+// killed, e.g. because a child process of a script still holds it open.
+const execWaitDelay = time.Second
+
+// exec executes the command in the working directory and returns its stdout. When the timeout is exceeded,
+// the command and its child processes are killed.
+func (l *loader) exec(command []string) ([]byte, error) {
+	timeout := l.opts.ExecTimeout
+	if timeout == 0 {
+		timeout = DefaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	killProcessGroup(cmd)
+	cmd.WaitDelay = execWaitDelay
+	cmd.Dir = l.opts.ExecDir
+	if cmd.Dir == "" && len(l.chain) > 0 {
+		cmd.Dir = filepath.Dir(l.chain[len(l.chain)-1])
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %v", timeout)
+	} else if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// relative returns the path relative to the folder of the file currently being resolved.
+func (l *loader) relative(path string) string {
+	if filepath.IsAbs(path) || len(l.chain) == 0 {
+		return path
+	}
+	return filepath.Join(filepath.Dir(l.chain[len(l.chain)-1]), path)
+}
+
+// errorf returns an error which contains the chain of included files.
+func (l *loader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s (include chain: %s)", fmt.Sprintf(format, args...), strings.Join(l.chain, " -> "))
+}
+
+// sortedKeys returns the sorted keys of the map, so fields are resolved in a deterministic order and
+// errors are reproducible.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
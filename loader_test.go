@@ -0,0 +1,102 @@
+package chew
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadChewable(t *testing.T) {
+	chewable, err := LoadChewable("test/data/include/main.json")
+	assert.NoError(t, err)
+
+	assert.Equal(t, &Chewable{
+		Global: map[string]interface{}{
+			"definitions": map[string]interface{}{
+				"id_column": map[string]interface{}{"name": "id", "type": "number"},
+			},
+			"columns": []interface{}{
+				map[string]interface{}{"name": "id", "type": "number"},
+				map[string]interface{}{"name": "name", "type": "string"},
+			},
+		},
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"table": "{{ .name }}.sql"},
+				Local:     map[string]interface{}{"name": "users"},
+			},
+		},
+	}, chewable)
+}
+
+func TestLoadChewable_Cycle(t *testing.T) {
+	_, err := LoadChewable("test/data/include/cycle_a.json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Include cycle detected: ")
+	assert.Contains(t, err.Error(), "cycle_a.json -> ")
+	assert.Contains(t, err.Error(), "cycle_b.json)")
+
+	_, err = LoadChewable("test/data/include/ref_cycle.json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Reference cycle detected: #/b")
+
+	_, err = LoadChewable("test/data/include/missing.json")
+	assert.Error(t, err)
+}
+
+func TestLoadChewableWithOptions_Exec(t *testing.T) {
+	_, err := LoadChewable("test/data/exec/main.json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Field '$exec' executes a command, which is disabled")
+
+	chewable, err := LoadChewableWithOptions("test/data/exec/main.json", LoadOptions{Exec: true})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"model": map[string]interface{}{
+			"tables": []interface{}{"users", "orders"},
+			"schema": "model",
+		},
+		"rows": map[string]interface{}{
+			"data": []interface{}{
+				map[string]interface{}{"name": "users", "templates": map[string]interface{}{"table": "users.sql"}},
+				map[string]interface{}{"name": "orders", "templates": map[string]interface{}{"table": "orders.sql"}},
+			},
+		},
+	}, chewable.Global)
+
+	_, err = LoadChewableWithOptions("test/data/exec/main.json", LoadOptions{Exec: true, ExecDir: "test/data"})
+	assert.Error(t, err)
+}
+
+func TestLoadChewableWithOptions_ExecError(t *testing.T) {
+	_, err := LoadChewableWithOptions("test/data/exec/fail.json", LoadOptions{Exec: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not execute command './fail.sh': exit status 3: database is not reachable")
+
+	_, err = LoadChewableWithOptions("test/data/exec/sleep.json", LoadOptions{Exec: true, ExecTimeout: 50 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not execute command 'sleep 5': timed out after 50ms")
+}
+
+func TestLoadChewableWithOptions_ExecTimeoutScript(t *testing.T) {
+	start := time.Now()
+	_, err := LoadChewableWithOptions("test/data/exec/slow.json", LoadOptions{Exec: true, ExecTimeout: 100 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not execute command './slow.sh': timed out after 100ms")
+	// the child process of the script is killed too, so the command doesn't block until it exits
+	assert.Less(t, int64(time.Since(start)), int64(execWaitDelay))
+}
+
+func TestLoadChewableWithOptions_Format(t *testing.T) {
+	_, err := LoadChewable("test/data/csv/codes.txt")
+	assert.Error(t, err)
+
+	chewable, err := LoadChewableWithOptions("test/data/csv/codes.txt", LoadOptions{Format: "csv"})
+	assert.NoError(t, err)
+	assert.Len(t, chewable.Data, 2)
+	assert.Equal(t, "Reduced, food", chewable.Data[1].Local["name"])
+
+	_, err = LoadChewableWithOptions("test/data/csv/codes.txt", LoadOptions{Format: "unknown"})
+	assert.EqualError(t, err, "Could not find a decoder for the format 'unknown'")
+}
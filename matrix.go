@@ -0,0 +1,100 @@
+package chew
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Matrix defines dimensions for which a ChewableData is expanded into the cartesian product of entries.
+// The key is the name of the dimension, the value is either a list of values or a string containing the
+// path to a list in the data of the ChewableData (nested fields are separated by dots):
+//
+//	{
+//	  "templates": {"dao": "{{ .dialect }}/{{ .entity.name }}.go"},
+//	  "matrix": {
+//	    "dialect": ["postgres", "mysql"],
+//	    "entity": "entities"
+//	  }
+//	}
+//
+// Every combination becomes a separate ChewableData where the value of each dimension is stored in Local
+// under the name of the dimension, so it can be used in templates and output filenames.
+type Matrix map[string]interface{}
+
+// expand returns a ChewableData for every combination of the dimension values. Dimensions are combined
+// in the alphabetical order of their names, the last dimension changes the fastest.
+func (m Matrix) expand(c Chewable, cd ChewableData) ([]ChewableData, error) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	data := prepareData(c, cd)
+	dimensions := make([][]interface{}, len(names))
+	for i, name := range names {
+		values, err := m.values(name, data)
+		if err != nil {
+			return nil, err
+		}
+		dimensions[i] = values
+	}
+
+	combinations := []map[string]interface{}{{}}
+	for i, name := range names {
+		next := make([]map[string]interface{}, 0, len(combinations)*len(dimensions[i]))
+		for _, combination := range combinations {
+			for _, value := range dimensions[i] {
+				nc := make(map[string]interface{}, len(combination)+1)
+				for k, v := range combination {
+					nc[k] = v
+				}
+				nc[name] = value
+				next = append(next, nc)
+			}
+		}
+		combinations = next
+	}
+
+	expanded := make([]ChewableData, len(combinations))
+	for i, combination := range combinations {
+		local := make(map[string]interface{}, len(cd.Local)+len(combination))
+		for k, v := range cd.Local {
+			local[k] = v
+		}
+		for k, v := range combination {
+			local[k] = v
+		}
+		expanded[i] = ChewableData{
+			Templates:  cd.Templates,
+			Local:      local,
+			When:       cd.When,
+			Conditions: cd.Conditions,
+		}
+	}
+
+	return expanded, nil
+}
+
+// values returns the values of the dimension with the provided name.
+func (m Matrix) values(name string, data map[string]interface{}) ([]interface{}, error) {
+	raw := m[name]
+	if path, ok := raw.(string); ok {
+		var found bool
+		if raw, found = lookupPath(data, path); !found {
+			return nil, fmt.Errorf("Could not find field '%s' for dimension '%s'", path, name)
+		}
+	}
+
+	val := reflect.ValueOf(raw)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil, fmt.Errorf("Dimension '%s' is not a slice", name)
+	}
+
+	values := make([]interface{}, val.Len())
+	for i := range values {
+		values[i] = val.Index(i).Interface()
+	}
+	return values, nil
+}
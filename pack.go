@@ -0,0 +1,258 @@
+package chew
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// PackManifest is the name of the file in a template pack which describes the pack.
+	PackManifest = "chew-pack.json"
+)
+
+// Pack describes a template pack, an archive (.zip or .tar.gz) containing a set of templates.
+// The description is read from the file PackManifest which can be stored anywhere in the archive.
+type Pack struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// ChewVersion is the minimal version of Chew that is required to use the templates in the pack.
+	ChewVersion string `json:"chew_version"`
+	// Templates are the names (without the template suffix) of the templates which are executed directly by
+	// the data, the other templates of the pack (e.g. layouts and plugins) are only executed by them.
+	Templates []string `json:"templates,omitempty"`
+}
+
+// IsPack returns true if the path points to a file which can be parsed as a template pack.
+func IsPack(path string) bool {
+	return strings.HasSuffix(path, ".zip") ||
+		strings.HasSuffix(path, ".tar.gz") ||
+		strings.HasSuffix(path, ".tgz")
+}
+
+// ParsePack reads the template pack stored in the archive with the provided path and parses every
+// template it contains via the same code path as ParseFolder. If the pack contains a manifest, the
+// required version of Chew is checked before any template is parsed and the manifest is appended
+// to Template.Packs. Packs without a manifest are parsed as well.
+func (ct *Template) ParsePack(packPath string) (*Template, error) {
+	files, err := readPack(packPath)
+	if err != nil {
+		return ct, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pack, err := readManifest(packPath, files, names)
+	if err != nil {
+		return ct, err
+	} else if pack != nil {
+		ct.Packs = append(ct.Packs, *pack)
+	}
+
+	for _, name := range names {
+		if !strings.Contains(name, templateSuffix) {
+			continue
+		}
+		if err := ct.parseTemplate(path.Base(name), files[name], packPath); err != nil {
+			return ct, err
+		}
+	}
+
+	return ct, nil
+}
+
+// ExtractPack writes all files of the template pack stored in the archive with the provided path into the folder
+// dir, keeping their paths in the archive, e.g. to customize the templates of the pack. Existing files are
+// overwritten. The required version of Chew and the paths of all files are checked before any file is written.
+// The manifest of the pack is returned, it is nil if the pack doesn't contain one.
+func ExtractPack(packPath, dir string) (*Pack, error) {
+	files, err := readPack(packPath)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pack, err := readManifest(packPath, files, names)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]string, len(names))
+	for i, name := range names {
+		targets[i] = filepath.Join(dir, filepath.FromSlash(name))
+		if rel, err := filepath.Rel(dir, targets[i]); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("Could not extract %s, the path is outside of %s", name, dir)
+		}
+	}
+
+	for i, name := range names {
+		if err := os.MkdirAll(filepath.Dir(targets[i]), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(targets[i], files[name], 0644); err != nil {
+			return nil, err
+		}
+	}
+	return pack, nil
+}
+
+// readManifest parses the first manifest found in the files of the template pack and checks the required
+// version of Chew. It returns nil if the pack doesn't contain a manifest.
+func readManifest(packPath string, files map[string][]byte, names []string) (*Pack, error) {
+	for _, name := range names {
+		if path.Base(name) != PackManifest {
+			continue
+		}
+
+		pack := &Pack{}
+		if err := json.Unmarshal(files[name], pack); err != nil {
+			return nil, fmt.Errorf("Could not parse manifest of template pack %s: %v", packPath, err)
+		}
+		if err := checkChewVersion(pack.ChewVersion); err != nil {
+			return nil, fmt.Errorf("Template pack %s %s: %v", pack.Name, pack.Version, err)
+		}
+		return pack, nil
+	}
+	return nil, nil
+}
+
+// readPack returns the content of all regular files in the archive mapped by their path.
+func readPack(packPath string) (map[string][]byte, error) {
+	if strings.HasSuffix(packPath, ".zip") {
+		return readZip(packPath)
+	}
+
+	f, err := os.Open(packPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readTarGz(f)
+}
+
+func readZip(packPath string) (map[string][]byte, error) {
+	r, err := zip.OpenReader(packPath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	files := make(map[string][]byte)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[f.Name] = content
+	}
+
+	return files, nil
+}
+
+func readTarGz(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		buffer := new(bytes.Buffer)
+		if _, err := io.Copy(buffer, tr); err != nil {
+			return nil, err
+		}
+		files[header.Name] = buffer.Bytes()
+	}
+
+	return files, nil
+}
+
+// checkChewVersion returns an error if the running version of Chew is older than the required one.
+// Development builds (without a version) pass every check.
+func checkChewVersion(required string) error {
+	if required == "" || Version == "" {
+		return nil
+	}
+
+	requiredParts, err := parseVersion(required)
+	if err != nil {
+		return err
+	}
+	currentParts, err := parseVersion(Version)
+	if err != nil {
+		// unknown version format, we can't compare it
+		return nil
+	}
+
+	for i := 0; i < len(requiredParts); i++ {
+		current := 0
+		if i < len(currentParts) {
+			current = currentParts[i]
+		}
+		if current > requiredParts[i] {
+			return nil
+		} else if current < requiredParts[i] {
+			return fmt.Errorf("requires Chew %s or newer, running %s", required, Version)
+		}
+	}
+	return nil
+}
+
+// parseVersion splits a version like v1.2.3 into its numeric parts. Pre-release suffixes are ignored.
+func parseVersion(version string) ([]int, error) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+
+	var parts []int
+	for _, p := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid version '%s'", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
@@ -0,0 +1,150 @@
+package chew
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testPackFiles = map[string]string{
+	"pack/" + PackManifest:          `{"name":"test","version":"1.0.0","chew_version":"0.2.0"}`,
+	"pack/test_indentTemplate.tmpl": "packed local variable:'{{ .local_var }}'",
+	"pack/nested/packed.tmpl":       "packed",
+}
+
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+}
+
+func writeTestTarGz(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		})
+		assert.NoError(t, err)
+		_, err = tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gw.Close())
+}
+
+func TestTemplate_ParsePack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chew")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	zipPath := filepath.Join(dir, "pack.zip")
+	tarPath := filepath.Join(dir, "pack.tar.gz")
+	writeTestZip(t, zipPath, testPackFiles)
+	writeTestTarGz(t, tarPath, testPackFiles)
+
+	for _, packPath := range []string{zipPath, tarPath} {
+		template := New("main")
+		_, err := template.ParseFolders("test/templates", packPath)
+		assert.NoError(t, err)
+
+		actual := template.IndentTemplate("test_indentTemplate", map[string]interface{}{"local_var": "test"}, nil, 0)
+		assert.Equal(t, "packed local variable:'test'", actual)
+		assert.Equal(t, packPath, template.Source("packed"))
+		assert.Equal(t, "test/templates", template.Source("test_plugins_main"))
+		assert.Equal(t, []Pack{{Name: "test", Version: "1.0.0", ChewVersion: "0.2.0"}}, template.Packs)
+	}
+}
+
+func TestTemplate_ParsePack_ChewVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chew")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	zipPath := filepath.Join(dir, "pack.zip")
+	writeTestZip(t, zipPath, testPackFiles)
+
+	defer func(v string) { Version = v }(Version)
+
+	testCases := []struct {
+		Version string
+		Error   bool
+	}{
+		{"", false},
+		{"0.2.0", false},
+		{"v0.2.1", false},
+		{"1.0", false},
+		{"0.1.9", true},
+		{"v0.1.12-rc1", true},
+	}
+
+	for _, tc := range testCases {
+		Version = tc.Version
+		_, err := New("main").ParsePack(zipPath)
+		if tc.Error {
+			assert.Error(t, err, tc.Version)
+		} else {
+			assert.NoError(t, err, tc.Version)
+		}
+	}
+}
+
+func TestExtractPack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chew")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	zipPath := filepath.Join(dir, "pack.zip")
+	writeTestZip(t, zipPath, testPackFiles)
+
+	out := filepath.Join(dir, "templates")
+	pack, err := ExtractPack(zipPath, out)
+	assert.NoError(t, err)
+	assert.Equal(t, &Pack{Name: "test", Version: "1.0.0", ChewVersion: "0.2.0"}, pack)
+
+	content, err := ioutil.ReadFile(filepath.Join(out, "pack", "nested", "packed.tmpl"))
+	assert.NoError(t, err)
+	assert.Equal(t, "packed", string(content))
+
+	template := New("main")
+	_, err = template.ParseFolder(out)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"packed", "test_indentTemplate"}, template.TemplateNames())
+
+	unsafePath := filepath.Join(dir, "unsafe.tar.gz")
+	writeTestTarGz(t, unsafePath, map[string]string{"../escaped.tmpl": "escaped"})
+	_, err = ExtractPack(unsafePath, out)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "escaped.tmpl"))
+	assert.True(t, os.IsNotExist(err))
+
+	// files before the unsafe path are not written either
+	out = filepath.Join(dir, "partial")
+	writeTestTarGz(t, unsafePath, map[string]string{"a.tmpl": "a", "z/../../escaped.tmpl": "escaped"})
+	_, err = ExtractPack(unsafePath, out)
+	assert.Error(t, err)
+	_, err = os.Stat(out)
+	assert.True(t, os.IsNotExist(err))
+}
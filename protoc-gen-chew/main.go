@@ -0,0 +1,175 @@
+// protoc-gen-chew is a protoc plugin which executes Chew templates on the files passed to protoc.
+//
+// The plugin reads a CodeGeneratorRequest from standard input and writes a CodeGeneratorResponse with
+// the generated files to standard output. The data is the same as when reading a descriptor set with
+// the package source/protobuf, only the files passed to protoc are used (not their imports).
+// The plugin is configured with a comma separated list of parameters:
+//   - templates=<path>: path to a folder or template pack with templates, can be repeated (a parameter
+//     without a key is also treated as a templates path)
+//   - message=<template>=<out>: template executed for every message
+//   - service=<template>=<out>: template executed for every service
+//   - template=<template>=<out>: template executed once with the global data
+//
+// For example:
+//
+//	protoc --chew_out=templates=./templates,message=model=model/{{.name}}.go:./gen shop/order.proto
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/lovromazgon/chew"
+	"github.com/lovromazgon/chew/source/protobuf"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func main() {
+	if err := run(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "protoc-gen-chew: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run reads the CodeGeneratorRequest from in and writes the CodeGeneratorResponse to out.
+func run(in io.Reader, out io.Writer) error {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(data, req); err != nil {
+		return fmt.Errorf("Could not decode CodeGeneratorRequest: %v", err)
+	}
+
+	data, err = proto.Marshal(generate(req))
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// generate executes the templates and returns the generated files. Errors are reported in the response,
+// as required by the plugin protocol.
+func generate(req *pluginpb.CodeGeneratorRequest) *pluginpb.CodeGeneratorResponse {
+	resp := &pluginpb.CodeGeneratorResponse{
+		SupportedFeatures: proto.Uint64(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)),
+	}
+
+	files, err := execute(req)
+	if err != nil {
+		resp.Error = proto.String(err.Error())
+		return resp
+	}
+	resp.File = files
+	return resp
+}
+
+func execute(req *pluginpb.CodeGeneratorRequest) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+	params, err := parseParameter(req.GetParameter())
+	if err != nil {
+		return nil, err
+	}
+
+	opts := protobuf.Options{
+		Files:            req.GetFileToGenerate(),
+		MessageTemplates: params.messageTemplates,
+		ServiceTemplates: params.serviceTemplates,
+	}
+	set, err := protobuf.NewSet(&descriptorpb.FileDescriptorSet{File: req.GetProtoFile()}, opts)
+	if err != nil {
+		return nil, err
+	}
+	chewable, err := protobuf.NewChewable(set, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.templates) > 0 {
+		chewable.Data = append(chewable.Data, chew.ChewableData{
+			Templates: params.templates,
+			Local:     map[string]interface{}{},
+		})
+	}
+
+	template := chew.New("main")
+	if _, err := template.ParseFolders(params.templatesPaths...); err != nil {
+		return nil, err
+	}
+
+	w := &chew.MemoryWriter{}
+	if err := template.ExecuteChewable(w, *chewable); err != nil {
+		return nil, err
+	}
+
+	files := make([]*pluginpb.CodeGeneratorResponse_File, 0, len(w.Files()))
+	for _, name := range w.Files() {
+		files = append(files, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(name),
+			Content: proto.String(string(w.Content(name))),
+		})
+	}
+	return files, nil
+}
+
+type parameters struct {
+	templatesPaths   []string
+	messageTemplates map[string]string
+	serviceTemplates map[string]string
+	templates        map[string]string
+}
+
+// parseParameter parses the parameter passed to the plugin (see the package documentation).
+func parseParameter(parameter string) (*parameters, error) {
+	params := &parameters{
+		messageTemplates: make(map[string]string),
+		serviceTemplates: make(map[string]string),
+		templates:        make(map[string]string),
+	}
+
+	for _, param := range strings.Split(parameter, ",") {
+		if param == "" {
+			continue
+		}
+
+		i := strings.Index(param, "=")
+		if i < 0 {
+			params.templatesPaths = append(params.templatesPaths, param)
+			continue
+		}
+		key, value := param[:i], param[i+1:]
+
+		var templates map[string]string
+		switch key {
+		case "templates":
+			params.templatesPaths = append(params.templatesPaths, value)
+			continue
+		case "message":
+			templates = params.messageTemplates
+		case "service":
+			templates = params.serviceTemplates
+		case "template":
+			templates = params.templates
+		default:
+			return nil, fmt.Errorf("Unknown parameter '%s'", key)
+		}
+
+		j := strings.Index(value, "=")
+		if j <= 0 {
+			return nil, fmt.Errorf("Invalid parameter '%s', expected %s=template=out", param, key)
+		}
+		templates[value[:j]] = value[j+1:]
+	}
+
+	if len(params.templatesPaths) == 0 {
+		return nil, errors.New("Templates path is required, pass it as parameter (e.g. --chew_out=templates=./templates:.)")
+	}
+	return params, nil
+}
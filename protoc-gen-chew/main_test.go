@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func testRequest(parameter string) *pluginpb.CodeGeneratorRequest {
+	return &pluginpb.CodeGeneratorRequest{
+		Parameter:      proto.String(parameter),
+		FileToGenerate: []string{"shop/order.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:        proto.String("common.proto"),
+				Package:     proto.String("common"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Money")}},
+			},
+			{
+				Name:        proto.String("shop/order.proto"),
+				Package:     proto.String("shop"),
+				Dependency:  []string{"common.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Order")}, {Name: proto.String("Line")}},
+			},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	req, err := proto.Marshal(testRequest("templates=testdata/templates,message=message={{.name}}.go,template=index=index.txt"))
+	assert.NoError(t, err)
+
+	out := new(bytes.Buffer)
+	err = run(bytes.NewReader(req), out)
+	assert.NoError(t, err)
+
+	resp := &pluginpb.CodeGeneratorResponse{}
+	assert.NoError(t, proto.Unmarshal(out.Bytes(), resp))
+	assert.Empty(t, resp.GetError())
+	assert.Equal(t, uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL), resp.GetSupportedFeatures())
+
+	files := make(map[string]string)
+	for _, file := range resp.GetFile() {
+		files[file.GetName()] = file.GetContent()
+	}
+	assert.Equal(t, map[string]string{
+		"Order.go":  "package shop\n\ntype Order struct{}\n",
+		"Line.go":   "package shop\n\ntype Line struct{}\n",
+		"index.txt": "shop.Order\nshop.Line\n",
+	}, files)
+}
+
+func TestGenerate_Error(t *testing.T) {
+	resp := generate(testRequest("message=message={{.name}}.go"))
+	assert.Contains(t, resp.GetError(), "Templates path is required")
+	assert.Empty(t, resp.GetFile())
+
+	resp = generate(testRequest("testdata/templates,message=message"))
+	assert.Equal(t, "Invalid parameter 'message=message', expected message=template=out", resp.GetError())
+
+	resp = generate(testRequest("testdata/templates,unknown=x"))
+	assert.Equal(t, "Unknown parameter 'unknown'", resp.GetError())
+
+	resp = generate(testRequest("testdata/templates,message=missing={{.name}}.go"))
+	assert.NotEmpty(t, resp.GetError())
+}
+
+func TestParseParameter(t *testing.T) {
+	params, err := parseParameter("a,templates=b,service=svc=out/{{.name}}.go")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, params.templatesPaths)
+	assert.Equal(t, map[string]string{"svc": "out/{{.name}}.go"}, params.serviceTemplates)
+	assert.Empty(t, params.messageTemplates)
+}
@@ -0,0 +1,589 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+type node interface {
+	eval(input interface{}) (interface{}, error)
+}
+
+type identityNode struct{}
+
+func (identityNode) eval(input interface{}) (interface{}, error) {
+	return input, nil
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(input interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type fieldNode struct {
+	target node
+	name   string
+}
+
+func (n fieldNode) eval(input interface{}) (interface{}, error) {
+	target, err := n.target.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	switch val := target.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return val[n.name], nil
+	}
+	return nil, fmt.Errorf("cannot get field '%s' of %s", n.name, typeName(target))
+}
+
+type indexNode struct {
+	target node
+	index  int
+}
+
+func (n indexNode) eval(input interface{}) (interface{}, error) {
+	target, err := n.target.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	switch val := target.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		i := n.index
+		if i < 0 {
+			i += len(val)
+		}
+		if i < 0 || i >= len(val) {
+			return nil, nil
+		}
+		return val[i], nil
+	}
+	return nil, fmt.Errorf("cannot get index %d of %s", n.index, typeName(target))
+}
+
+type pipeNode struct {
+	left, right node
+}
+
+func (n pipeNode) eval(input interface{}) (interface{}, error) {
+	left, err := n.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	return n.right.eval(left)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(input interface{}) (interface{}, error) {
+	left, err := n.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	c := compare(left, right)
+	switch n.op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator '%s'", n.op)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n andNode) eval(input interface{}) (interface{}, error) {
+	left, err := n.left.eval(input)
+	if err != nil || !truthy(left) {
+		return false, err
+	}
+	right, err := n.right.eval(input)
+	return truthy(right), err
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n orNode) eval(input interface{}) (interface{}, error) {
+	left, err := n.left.eval(input)
+	if err != nil || truthy(left) {
+		return true, err
+	}
+	right, err := n.right.eval(input)
+	return truthy(right), err
+}
+
+type notNode struct {
+	n node
+}
+
+func (n notNode) eval(input interface{}) (interface{}, error) {
+	v, err := n.n.eval(input)
+	return !truthy(v), err
+}
+
+type arrayNode struct {
+	elems []node
+}
+
+func (n arrayNode) eval(input interface{}) (interface{}, error) {
+	arr := make([]interface{}, len(n.elems))
+	for i, elem := range n.elems {
+		v, err := elem.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+	return arr, nil
+}
+
+type objectNode struct {
+	keys   []string
+	values []node
+}
+
+func (n objectNode) eval(input interface{}) (interface{}, error) {
+	obj := make(map[string]interface{}, len(n.keys))
+	for i, key := range n.keys {
+		v, err := n.values[i].eval(input)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = v
+	}
+	return obj, nil
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n callNode) eval(input interface{}) (interface{}, error) {
+	v, err := n.fn.call(input, n.args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", n.name, err)
+	}
+	return v, nil
+}
+
+// ----------------------------------------------------------------
+
+type function struct {
+	minArgs, maxArgs int
+	call             func(input interface{}, args []node) (interface{}, error)
+}
+
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"select":  {1, 1, selectFunc},
+		"map":     {1, 1, mapFunc},
+		"group":   {1, 1, groupFunc},
+		"sort":    {0, 1, sortFunc},
+		"unique":  {0, 1, uniqueFunc},
+		"reverse": {0, 0, reverseFunc},
+		"first":   {0, 0, firstFunc},
+		"last":    {0, 0, lastFunc},
+		"length":  {0, 0, lengthFunc},
+		"keys":    {0, 0, keysFunc},
+		"has":     {1, 1, hasFunc},
+	}
+}
+
+func selectFunc(input interface{}, args []node) (interface{}, error) {
+	arr, err := array(input)
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]interface{}, 0, len(arr))
+	for _, elem := range arr {
+		v, err := args[0].eval(elem)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(v) {
+			selected = append(selected, elem)
+		}
+	}
+	return selected, nil
+}
+
+func mapFunc(input interface{}, args []node) (interface{}, error) {
+	arr, err := array(input)
+	if err != nil {
+		return nil, err
+	}
+	mapped := make([]interface{}, len(arr))
+	for i, elem := range arr {
+		if mapped[i], err = args[0].eval(elem); err != nil {
+			return nil, err
+		}
+	}
+	return mapped, nil
+}
+
+func groupFunc(input interface{}, args []node) (interface{}, error) {
+	arr, err := array(input)
+	if err != nil {
+		return nil, err
+	}
+	var groups []interface{}
+	for _, elem := range arr {
+		key, err := args[0].eval(elem)
+		if err != nil {
+			return nil, err
+		}
+
+		var group map[string]interface{}
+		for _, g := range groups {
+			if compare(g.(map[string]interface{})["key"], key) == 0 {
+				group = g.(map[string]interface{})
+				break
+			}
+		}
+		if group == nil {
+			group = map[string]interface{}{"key": key, "items": []interface{}{}}
+			groups = append(groups, group)
+		}
+		group["items"] = append(group["items"].([]interface{}), elem)
+	}
+	if groups == nil {
+		groups = []interface{}{}
+	}
+	return groups, nil
+}
+
+func sortFunc(input interface{}, args []node) (interface{}, error) {
+	arr, keys, err := arrayWithKeys(input, args)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := make([]int, len(arr))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return compare(keys[indices[i]], keys[indices[j]]) < 0
+	})
+
+	sorted := make([]interface{}, len(arr))
+	for i, index := range indices {
+		sorted[i] = arr[index]
+	}
+	return sorted, nil
+}
+
+func uniqueFunc(input interface{}, args []node) (interface{}, error) {
+	arr, keys, err := arrayWithKeys(input, args)
+	if err != nil {
+		return nil, err
+	}
+
+	unique := make([]interface{}, 0, len(arr))
+	var seen []interface{}
+	for i, elem := range arr {
+		duplicate := false
+		for _, s := range seen {
+			if compare(s, keys[i]) == 0 {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			seen = append(seen, keys[i])
+			unique = append(unique, elem)
+		}
+	}
+	return unique, nil
+}
+
+func reverseFunc(input interface{}, args []node) (interface{}, error) {
+	arr, err := array(input)
+	if err != nil {
+		return nil, err
+	}
+	reversed := make([]interface{}, len(arr))
+	for i, elem := range arr {
+		reversed[len(arr)-1-i] = elem
+	}
+	return reversed, nil
+}
+
+func firstFunc(input interface{}, args []node) (interface{}, error) {
+	arr, err := array(input)
+	if err != nil || len(arr) == 0 {
+		return nil, err
+	}
+	return arr[0], nil
+}
+
+func lastFunc(input interface{}, args []node) (interface{}, error) {
+	arr, err := array(input)
+	if err != nil || len(arr) == 0 {
+		return nil, err
+	}
+	return arr[len(arr)-1], nil
+}
+
+func lengthFunc(input interface{}, args []node) (interface{}, error) {
+	switch val := input.(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(len([]rune(val))), nil
+	case []interface{}:
+		return float64(len(val)), nil
+	case map[string]interface{}:
+		return float64(len(val)), nil
+	}
+	return nil, fmt.Errorf("%s has no length", typeName(input))
+}
+
+func keysFunc(input interface{}, args []node) (interface{}, error) {
+	obj, ok := input.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not an object", typeName(input))
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]interface{}, len(keys))
+	for i, k := range keys {
+		result[i] = k
+	}
+	return result, nil
+}
+
+func hasFunc(input interface{}, args []node) (interface{}, error) {
+	obj, ok := input.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not an object", typeName(input))
+	}
+	key, err := args[0].eval(input)
+	if err != nil {
+		return nil, err
+	}
+	keyStr, ok := key.(string)
+	if !ok {
+		return nil, fmt.Errorf("key %v is not a string", key)
+	}
+	_, exists := obj[keyStr]
+	return exists, nil
+}
+
+// ----------------------------------------------------------------
+
+func array(input interface{}) ([]interface{}, error) {
+	switch val := input.(type) {
+	case nil:
+		return []interface{}{}, nil
+	case []interface{}:
+		return val, nil
+	}
+	return nil, fmt.Errorf("%s is not an array", typeName(input))
+}
+
+// arrayWithKeys returns the input array and the key of each element, which is the value of the optional
+// argument evaluated on the element or the element itself.
+func arrayWithKeys(input interface{}, args []node) ([]interface{}, []interface{}, error) {
+	arr, err := array(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(args) == 0 {
+		return arr, arr, nil
+	}
+
+	keys := make([]interface{}, len(arr))
+	for i, elem := range arr {
+		if keys[i], err = args[0].eval(elem); err != nil {
+			return nil, nil, err
+		}
+	}
+	return arr, keys, nil
+}
+
+func truthy(v interface{}) bool {
+	return v != nil && v != false
+}
+
+// order returns the position of the type in the sort order: null, false, true, numbers, strings, arrays, objects.
+func order(v interface{}) int {
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if !val {
+			return 1
+		}
+		return 2
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	}
+	return 6
+}
+
+// compare returns -1, 0 or 1 if a is less than, equal to or greater than b.
+func compare(a, b interface{}) int {
+	oa, ob := order(a), order(b)
+	if oa != ob {
+		if oa < ob {
+			return -1
+		}
+		return 1
+	}
+
+	switch va := a.(type) {
+	case float64:
+		vb := b.(float64)
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+		return 0
+	case string:
+		vb := b.(string)
+		if va < vb {
+			return -1
+		} else if va > vb {
+			return 1
+		}
+		return 0
+	case []interface{}:
+		vb := b.([]interface{})
+		for i := 0; i < len(va) && i < len(vb); i++ {
+			if c := compare(va[i], vb[i]); c != 0 {
+				return c
+			}
+		}
+		return compare(float64(len(va)), float64(len(vb)))
+	case map[string]interface{}:
+		if reflect.DeepEqual(a, b) {
+			return 0
+		}
+		ka, _ := keysFunc(a, nil)
+		kb, _ := keysFunc(b, nil)
+		if c := compare(ka, kb); c != 0 {
+			return c
+		}
+		for _, k := range ka.([]interface{}) {
+			if c := compare(va[k.(string)], b.(map[string]interface{})[k.(string)]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// normalize converts Go values to the types produced by encoding/json (nil, bool, float64, string,
+// []interface{} and map[string]interface{}), so the query can operate on values created in Go.
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil, bool, float64, string:
+		return v
+	case []interface{}:
+		normalized := make([]interface{}, len(val))
+		for i, elem := range val {
+			normalized[i] = normalize(elem)
+		}
+		return normalized
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(val))
+		for k, elem := range val {
+			normalized[k] = normalize(elem)
+		}
+		return normalized
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		normalized := make([]interface{}, rv.Len())
+		for i := range normalized {
+			normalized[i] = normalize(rv.Index(i).Interface())
+		}
+		return normalized
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			normalized := make(map[string]interface{}, rv.Len())
+			for _, k := range rv.MapKeys() {
+				normalized[k.String()] = normalize(rv.MapIndex(k).Interface())
+			}
+			return normalized
+		}
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	}
+	return v
+}
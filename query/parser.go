@@ -0,0 +1,453 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenError
+	tokenDot
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenPipe
+	tokenComma
+	tokenColon
+	tokenAssign
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenLBrace
+	tokenRBrace
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("'%s' at position %d", t.text, t.pos)
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src}
+}
+
+func (l *lexer) next() token {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: l.pos}
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	single := map[byte]tokenKind{
+		'.': tokenDot,
+		'|': tokenPipe,
+		',': tokenComma,
+		':': tokenColon,
+		'(': tokenLParen,
+		')': tokenRParen,
+		'[': tokenLBracket,
+		']': tokenRBracket,
+		'{': tokenLBrace,
+		'}': tokenRBrace,
+	}
+
+	switch {
+	case c == '=' || c == '!' || c == '<' || c == '>':
+		l.pos++
+		if l.pos < len(l.src) && l.src[l.pos] == '=' {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		switch text {
+		case "=":
+			return token{kind: tokenAssign, text: text, pos: start}
+		case "!":
+			return token{kind: tokenError, text: text, pos: start}
+		}
+		return token{kind: tokenOp, text: text, pos: start}
+
+	case single[c] != 0:
+		l.pos++
+		return token{kind: single[c], text: string(c), pos: start}
+
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return token{kind: tokenError, text: l.src[start:], pos: start}
+		}
+		l.pos++
+		text := l.src[start:l.pos]
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return token{kind: tokenError, text: text, pos: start}
+		}
+		return token{kind: tokenString, text: text, value: value, pos: start}
+
+	case c == '-' || (c >= '0' && c <= '9'):
+		l.pos++
+		for l.pos < len(l.src) && strings.IndexByte("0123456789.eE", l.src[l.pos]) >= 0 {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return token{kind: tokenError, text: text, pos: start}
+		}
+		return token{kind: tokenNumber, text: text, value: value, pos: start}
+
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || unicode.IsLetter(rune(l.src[l.pos])) || unicode.IsDigit(rune(l.src[l.pos]))) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos], pos: start}
+	}
+
+	l.pos++
+	return token{kind: tokenError, text: string(c), pos: start}
+}
+
+// parser is a recursive descent parser with one token of lookahead.
+type parser struct {
+	lexer *lexer
+	tok   token
+	init  bool
+}
+
+func (p *parser) peek() token {
+	if !p.init {
+		p.tok = p.lexer.next()
+		p.init = true
+	}
+	return p.tok
+}
+
+func (p *parser) advance() token {
+	tok := p.peek()
+	p.tok = p.lexer.next()
+	return tok
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.advance()
+	if tok.kind != kind {
+		return tok, fmt.Errorf("expected %s, got %s", what, tok)
+	}
+	return tok, nil
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s", tok)
+	}
+	return n, nil
+}
+
+func (p *parser) pipeline() (node, error) {
+	left, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenPipe {
+		p.advance()
+		right, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		left = pipeNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.advance()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.advance()
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tokenOp {
+		op := p.advance().text
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{op, left, right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.isKeyword("not") {
+		p.advance()
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokenDot:
+			dot := p.advance()
+			if n, err = p.field(n, dot); err != nil {
+				return nil, err
+			}
+		case tokenLBracket:
+			if n, err = p.index(n); err != nil {
+				return nil, err
+			}
+		default:
+			return n, nil
+		}
+	}
+}
+
+// field parses the name of a field after a dot, the name has to follow the dot without whitespace.
+func (p *parser) field(target node, dot token) (node, error) {
+	tok := p.advance()
+	if tok.pos != dot.pos+1 {
+		return nil, fmt.Errorf("expected field name directly after '.' at position %d, got %s", dot.pos, tok)
+	}
+	switch tok.kind {
+	case tokenIdent:
+		return fieldNode{target, tok.text}, nil
+	case tokenString:
+		return fieldNode{target, tok.value.(string)}, nil
+	}
+	return nil, fmt.Errorf("expected field name, got %s", tok)
+}
+
+// index parses an index in brackets.
+func (p *parser) index(target node) (node, error) {
+	p.advance()
+	tok, err := p.expect(tokenNumber, "index")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenRBracket, "']'"); err != nil {
+		return nil, err
+	}
+	index := tok.value.(float64)
+	if index != math.Trunc(index) {
+		return nil, fmt.Errorf("expected integer index, got %s", tok)
+	}
+	return indexNode{target, int(index)}, nil
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case tokenDot:
+		dot := p.advance()
+		switch next := p.peek(); next.kind {
+		case tokenIdent, tokenString:
+			if next.pos != dot.pos+1 {
+				// whitespace after the dot, e.g. in ". and .x"
+				return identityNode{}, nil
+			}
+			return p.field(identityNode{}, dot)
+		case tokenLBracket:
+			return p.index(identityNode{})
+		}
+		return identityNode{}, nil
+
+	case tokenString, tokenNumber:
+		p.advance()
+		return literalNode{tok.value}, nil
+
+	case tokenIdent:
+		p.advance()
+		switch tok.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null":
+			return literalNode{nil}, nil
+		}
+		return p.call(tok)
+
+	case tokenLParen:
+		p.advance()
+		n, err := p.pipeline()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case tokenLBracket:
+		p.advance()
+		elems, err := p.list(tokenRBracket, "']'")
+		if err != nil {
+			return nil, err
+		}
+		return arrayNode{elems}, nil
+
+	case tokenLBrace:
+		p.advance()
+		return p.object()
+	}
+
+	return nil, fmt.Errorf("unexpected %s", tok)
+}
+
+func (p *parser) call(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+
+	var args []node
+	if p.peek().kind == tokenLParen {
+		p.advance()
+		var err error
+		if args, err = p.list(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(args) < fn.minArgs || len(args) > fn.maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments for function %s", name)
+	}
+	return callNode{name.text, fn, args}, nil
+}
+
+// list parses pipelines separated by commas until the closing token.
+func (p *parser) list(closing tokenKind, what string) ([]node, error) {
+	var nodes []node
+	if p.peek().kind == closing {
+		p.advance()
+		return nodes, nil
+	}
+	for {
+		n, err := p.pipeline()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+
+		tok := p.advance()
+		if tok.kind == closing {
+			return nodes, nil
+		} else if tok.kind != tokenComma {
+			return nil, fmt.Errorf("expected ',' or %s, got %s", what, tok)
+		}
+	}
+}
+
+func (p *parser) object() (node, error) {
+	obj := objectNode{}
+	if p.peek().kind == tokenRBrace {
+		p.advance()
+		return obj, nil
+	}
+	for {
+		tok := p.advance()
+		var key string
+		switch tok.kind {
+		case tokenIdent:
+			key = tok.text
+		case tokenString:
+			key = tok.value.(string)
+		default:
+			return nil, fmt.Errorf("expected object key, got %s", tok)
+		}
+		if _, err := p.expect(tokenColon, "':'"); err != nil {
+			return nil, err
+		}
+		value, err := p.pipeline()
+		if err != nil {
+			return nil, err
+		}
+		obj.keys = append(obj.keys, key)
+		obj.values = append(obj.values, value)
+
+		tok = p.advance()
+		if tok.kind == tokenRBrace {
+			return obj, nil
+		} else if tok.kind != tokenComma {
+			return nil, fmt.Errorf("expected ',' or '}', got %s", tok)
+		}
+	}
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == tokenIdent && tok.text == keyword
+}
@@ -0,0 +1,152 @@
+// Package query implements a small jq-style query language used to transform data before it is
+// processed by Chew.
+//
+// A query is a pipeline of expressions separated by |, where the output of each expression is the
+// input of the next one. Unlike jq there are no streams, every expression produces exactly one value.
+//
+// Expressions:
+//
+//	.                    the input value
+//	.a.b[0]              a field or element of the input value (missing fields are null)
+//	"text", 1.5, true,   literals
+//	false, null
+//	[a, b]               array of the values of expressions a and b
+//	{name: .a, n: 1}     object with the values of the expressions
+//	a == b, a != b,      comparison of numbers, strings and other values
+//	a < b, a <= b,
+//	a > b, a >= b
+//	a and b, a or b,     boolean logic, false and null are falsy, everything else is truthy
+//	not a
+//	(pipeline)           grouping
+//
+// Functions operating on arrays:
+//
+//	select(cond)         keeps the elements for which cond is truthy
+//	map(f)               replaces every element with the value of f
+//	group(f)             groups the elements by the value of f into objects {"key": ..., "items": [...]},
+//	                     groups are in the order of their first occurrence
+//	sort, sort(f)        sorts the elements (by the value of f)
+//	unique, unique(f)    removes duplicate elements (by the value of f), keeps the first occurrence
+//	reverse, first, last
+//
+// Other functions:
+//
+//	length               length of an array, object or string
+//	keys                 sorted keys of an object
+//	has(key)             true if the object contains the key
+//
+// Example:
+//
+//	.tables | select(.kind == "entity" and not .deprecated) | sort(.name) | map({name: .name, columns: (.columns | length)})
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query is a compiled query.
+type Query struct {
+	src  string
+	root node
+}
+
+// Compile parses the query.
+func Compile(src string) (*Query, error) {
+	p := &parser{lexer: newLexer(src)}
+	root, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse query '%s': %v", src, err)
+	}
+	return &Query{src: src, root: root}, nil
+}
+
+// Run executes the query on the input value and returns the result.
+func (q *Query) Run(input interface{}) (interface{}, error) {
+	result, err := q.root.eval(normalize(input))
+	if err != nil {
+		return nil, fmt.Errorf("Could not run query '%s': %v", q.src, err)
+	}
+	return result, nil
+}
+
+// String returns the source of the query.
+func (q *Query) String() string {
+	return q.src
+}
+
+// Transform applies a transformation to the document. A transformation is either a query, in which case
+// its result replaces the whole document, or an assignment in the form `.path = query`, in which case the
+// result of the query (executed on the whole document) is stored in the document on the path. Missing
+// objects on the path are created. The document is not modified, a modified copy is returned.
+func Transform(doc interface{}, src string) (interface{}, error) {
+	target, querySrc := splitAssignment(src)
+
+	q, err := Compile(querySrc)
+	if err != nil {
+		return nil, err
+	}
+	result, err := q.Run(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if target == nil {
+		return result, nil
+	}
+	return assign(normalize(doc), target, result)
+}
+
+// splitAssignment splits the transformation into the path and the query if it is an assignment.
+func splitAssignment(src string) ([]string, string) {
+	l := newLexer(src)
+	var path []string
+
+	tok := l.next()
+	if tok.kind != tokenDot {
+		return nil, src
+	}
+	for {
+		tok = l.next()
+		if tok.kind == tokenIdent {
+			path = append(path, tok.text)
+		} else if tok.kind == tokenString {
+			path = append(path, tok.value.(string))
+		} else {
+			return nil, src
+		}
+
+		tok = l.next()
+		if tok.kind == tokenAssign {
+			return path, strings.TrimSpace(src[tok.pos+1:])
+		} else if tok.kind != tokenDot {
+			return nil, src
+		}
+	}
+}
+
+func assign(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	var obj map[string]interface{}
+	switch val := doc.(type) {
+	case nil:
+		obj = make(map[string]interface{})
+	case map[string]interface{}:
+		obj = make(map[string]interface{}, len(val)+1)
+		for k, v := range val {
+			obj[k] = v
+		}
+	default:
+		return nil, fmt.Errorf("Could not assign field '%s', value is not an object", path[0])
+	}
+
+	child, err := assign(obj[path[0]], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	obj[path[0]] = child
+	return obj, nil
+}
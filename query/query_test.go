@@ -0,0 +1,142 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testDoc = map[string]interface{}{
+	"name": "shop",
+	"tables": []interface{}{
+		map[string]interface{}{"name": "users", "kind": "entity", "columns": []interface{}{"id", "name"}},
+		map[string]interface{}{"name": "orders", "kind": "entity", "columns": []interface{}{"id"}, "deprecated": true},
+		map[string]interface{}{"name": "audit", "kind": "log", "columns": []interface{}{}},
+		map[string]interface{}{"name": "accounts", "kind": "entity", "columns": []interface{}{"id", "owner", "balance"}},
+	},
+	"limits": []int{3, 1, 2},
+}
+
+func TestQuery_Run(t *testing.T) {
+	testCases := []struct {
+		Query    string
+		Expected interface{}
+	}{
+		{". | .name", "shop"},
+		{".name", "shop"},
+		{`."name"`, "shop"},
+		{". and .name", true},
+		{".missing.field", nil},
+		{".tables[1].name", "orders"},
+		{".tables[-1].name", "accounts"},
+		{".tables[10]", nil},
+		{".limits | sort", []interface{}{float64(1), float64(2), float64(3)}},
+		{".limits | sort | reverse | first", float64(3)},
+		{".tables | length", float64(4)},
+		{".tables | map(.name)", []interface{}{"users", "orders", "audit", "accounts"}},
+		{`.tables | select(.kind == "entity" and not .deprecated) | map(.name)`, []interface{}{"users", "accounts"}},
+		{`.tables | select(.kind != "entity" or .deprecated) | map(.name)`, []interface{}{"orders", "audit"}},
+		{`.tables | select((.columns | length) >= 2) | sort(.name) | map(.name)`, []interface{}{"accounts", "users"}},
+		{".tables | sort(.columns | length) | last | .name", "accounts"},
+		{".tables | map(.kind) | unique", []interface{}{"entity", "log"}},
+		{".tables | unique(.kind) | map(.name)", []interface{}{"users", "audit"}},
+		{".tables | group(.kind) | map({kind: .key, count: (.items | length)})", []interface{}{
+			map[string]interface{}{"kind": "entity", "count": float64(3)},
+			map[string]interface{}{"kind": "log", "count": float64(1)},
+		}},
+		{`[.name, 1, true, null, "x"]`, []interface{}{"shop", float64(1), true, nil, "x"}},
+		{`{"a b": .name} | keys`, []interface{}{"a b"}},
+		{`.tables | first | has("deprecated")`, false},
+		{`1 < 2`, true},
+		{`"a" > "b"`, false},
+		{`null < false`, true},
+		{`[1, 2] == [1, 2]`, true},
+		{`{a: 1} != {a: 1}`, false},
+	}
+
+	for _, tc := range testCases {
+		q, err := Compile(tc.Query)
+		if !assert.NoError(t, err, tc.Query) {
+			continue
+		}
+		actual, err := q.Run(testDoc)
+		assert.NoError(t, err, tc.Query)
+		assert.Equal(t, tc.Expected, actual, tc.Query)
+	}
+}
+
+func TestCompile_Error(t *testing.T) {
+	testCases := []string{
+		"",
+		".name |",
+		".tables[",
+		".tables[a]",
+		"unknown(.name)",
+		"select",
+		"map(.a, .b)",
+		"{a .b}",
+		"[1, 2",
+		`"unterminated`,
+		".a ! .b",
+		".a = 1",
+		". and",
+		". name",
+		".tables. name",
+		".tables[0.5]",
+	}
+
+	for _, tc := range testCases {
+		_, err := Compile(tc)
+		assert.Error(t, err, tc)
+	}
+}
+
+func TestQuery_Run_Error(t *testing.T) {
+	testCases := []string{
+		".name.first",
+		".name[0]",
+		".name | map(.)",
+		".name | keys",
+		"1 | length",
+		`.tables | has("name")`,
+	}
+
+	for _, tc := range testCases {
+		q, err := Compile(tc)
+		if !assert.NoError(t, err, tc) {
+			continue
+		}
+		_, err = q.Run(testDoc)
+		assert.Error(t, err, tc)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	doc := map[string]interface{}{
+		"tables": []interface{}{
+			map[string]interface{}{"name": "users"},
+			map[string]interface{}{"name": "orders"},
+		},
+	}
+
+	actual, err := Transform(doc, ".model.names = .tables | map(.name) | sort")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tables": doc["tables"],
+		"model": map[string]interface{}{
+			"names": []interface{}{"orders", "users"},
+		},
+	}, actual)
+	assert.NotContains(t, doc, "model")
+
+	actual, err = Transform(doc, ".tables | first")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "users"}, actual)
+
+	actual, err = Transform(doc, `.tables == .tables`)
+	assert.NoError(t, err)
+	assert.Equal(t, true, actual)
+
+	_, err = Transform(doc, ".tables.name = 1")
+	assert.Error(t, err)
+}
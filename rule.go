@@ -0,0 +1,126 @@
+package chew
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Rule generates ChewableData for every element of a list in Chewable.Global, so that the same template
+// mapping doesn't have to be repeated in every entry. Rules are defined in the top-level field 'rules':
+//
+//	"rules": [
+//	  {"for": "entities", "template": "entity", "out": "{{ .name }}.go"}
+//	]
+//
+// The field For contains the path to the list in Chewable.Global (nested fields are separated by dots).
+// Every element of the list becomes the Local data of a ChewableData which executes Template. The output
+// filename Out can contain template actions which are evaluated on the data of the element. If Out is
+// empty, the output pattern from the front matter of the template is used. The optional condition When
+// is copied to every created ChewableData (see ChewableData.When).
+type Rule struct {
+	For      string
+	Template string
+	Out      string
+	When     string
+}
+
+func extractRule(data interface{}) (rule Rule, err error) {
+	ruleMap, err := ToMap(data)
+	if err != nil {
+		return rule, err
+	}
+
+	fields := map[string]*string{
+		"for":      &rule.For,
+		"template": &rule.Template,
+		"out":      &rule.Out,
+		"when":     &rule.When,
+	}
+	for field, target := range fields {
+		raw, ok := ruleMap[field]
+		if !ok {
+			continue
+		}
+		str, ok := raw.(string)
+		if !ok {
+			return rule, fmt.Errorf("Field '%s' is not a string", field)
+		}
+		*target = str
+	}
+
+	if rule.For == "" {
+		return rule, errors.New("Could not find field 'for'")
+	} else if rule.Template == "" {
+		return rule, errors.New("Could not find field 'template'")
+	}
+	return rule, nil
+}
+
+func (r Rule) toMap() map[string]interface{} {
+	doc := map[string]interface{}{
+		"for":      r.For,
+		"template": r.Template,
+	}
+	if r.Out != "" {
+		doc["out"] = r.Out
+	}
+	if r.When != "" {
+		doc["when"] = r.When
+	}
+	return doc
+}
+
+// expand creates a ChewableData for every element in the list defined by the rule.
+func (r Rule) expand(global map[string]interface{}) ([]ChewableData, error) {
+	listRaw, ok := lookupPath(global, r.For)
+	if !ok {
+		return nil, fmt.Errorf("Could not find field '%s'", r.For)
+	}
+
+	listVal := reflect.ValueOf(listRaw)
+	if listVal.Kind() != reflect.Slice && listVal.Kind() != reflect.Array {
+		return nil, fmt.Errorf("Field '%s' is not a slice", r.For)
+	}
+
+	data := make([]ChewableData, listVal.Len())
+	for i := 0; i < listVal.Len(); i++ {
+		elem, err := ToMap(listVal.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("Could not extract object %d in field '%s': %v", i, r.For, err)
+		}
+
+		local := make(map[string]interface{}, len(elem))
+		for k, v := range elem {
+			local[k] = v
+		}
+
+		data[i] = ChewableData{
+			Templates: map[string]string{r.Template: r.Out},
+			Local:     local,
+			When:      r.When,
+		}
+	}
+
+	return data, nil
+}
+
+// lookupPath returns the value in data on the provided path, where nested fields are separated by dots.
+func lookupPath(data map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = data
+	for _, field := range strings.Split(path, ".") {
+		if current == nil {
+			return nil, false
+		}
+		currentMap, err := ToMap(current)
+		if err != nil {
+			return nil, false
+		}
+		var ok bool
+		if current, ok = currentMap[field]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
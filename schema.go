@@ -0,0 +1,471 @@
+package chew
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a JSON Schema which can be used to validate a Chewable before it is executed. The Chewable
+// is validated in its document form, the same form as in the JSON data file: the fields of Chewable.Global
+// are at the top level next to the list 'data', where each object contains the fields of ChewableData.Local
+// and the field 'templates'.
+//
+// The following keywords are supported: type, enum, const, properties, required, additionalProperties,
+// patternProperties, minProperties, maxProperties, items, minItems, maxItems, uniqueItems, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, minLength, maxLength, pattern, allOf, anyOf, oneOf, not and
+// local references ($ref starting with #) to definitions in the same schema.
+type Schema struct {
+	root interface{}
+}
+
+// SchemaError describes a value which doesn't match the schema. The location of the value is defined
+// by a JSON pointer.
+type SchemaError struct {
+	Pointer string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	pointer := e.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return pointer + ": " + e.Message
+}
+
+// SchemaErrors contains all errors found when validating data with a schema.
+type SchemaErrors []SchemaError
+
+func (e SchemaErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "Data does not match schema:\n" + strings.Join(messages, "\n")
+}
+
+// LoadSchema reads and parses the JSON Schema stored in the file with the provided path.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ParseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse schema %s: %v", path, err)
+	}
+	return schema, nil
+}
+
+// ParseSchema parses a JSON Schema.
+func ParseSchema(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	switch root.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return nil, fmt.Errorf("Schema has to be an object or a boolean")
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate validates the document form of Chewable with the schema. If the data doesn't match the schema
+// SchemaErrors are returned.
+func (s *Schema) Validate(c Chewable) error {
+	return s.ValidateValue(c.ToDoc())
+}
+
+// ValidateValue validates an arbitrary value with the schema. If the value doesn't match the schema
+// SchemaErrors are returned.
+func (s *Schema) ValidateValue(v interface{}) error {
+	errs := s.validate(s.root, v, "", nil)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate validates the value at the pointer with the schema. refs contains the references which are
+// resolved for the value at the pointer, a reference which is resolved again is a cycle which would never end.
+func (s *Schema) validate(schemaRaw interface{}, v interface{}, pointer string, refs []string) SchemaErrors {
+	if b, ok := schemaRaw.(bool); ok {
+		if !b {
+			return SchemaErrors{{pointer, "no value is allowed"}}
+		}
+		return nil
+	}
+	schema, ok := schemaRaw.(map[string]interface{})
+	if !ok {
+		return SchemaErrors{{pointer, "invalid schema"}}
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		key := pointer + "#" + ref
+		for _, r := range refs {
+			if r == key {
+				return SchemaErrors{{pointer, fmt.Sprintf("reference cycle detected: %s", ref)}}
+			}
+		}
+		resolved, err := s.resolve(ref)
+		if err != nil {
+			return SchemaErrors{{pointer, err.Error()}}
+		}
+		return s.validate(resolved, v, pointer, append(refs[:len(refs):len(refs)], key))
+	}
+
+	v = normalizeValue(v)
+	var errs SchemaErrors
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, SchemaError{pointer, fmt.Sprintf(format, args...)})
+	}
+
+	if typ, ok := schema["type"]; ok && !matchesType(v, typ) {
+		fail("expected %s, got %s", typeString(typ), jsonType(v))
+		return errs
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(normalizeValue(e), v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value is not one of %v", enum)
+		}
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(normalizeValue(c), v) {
+		fail("value is not %v", c)
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		errs = append(errs, s.validateObject(schema, val, pointer)...)
+	case []interface{}:
+		errs = append(errs, s.validateArray(schema, val, pointer)...)
+	case float64:
+		errs = append(errs, validateNumber(schema, val, pointer)...)
+	case string:
+		errs = append(errs, validateString(schema, val, pointer)...)
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			errs = append(errs, s.validate(sub, v, pointer, refs)...)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if len(s.validate(sub, v, pointer, refs)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("value does not match any schema in anyOf")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, sub := range oneOf {
+			if len(s.validate(sub, v, pointer, refs)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("value matches %d schemas in oneOf instead of exactly one", matched)
+		}
+	}
+	if not, ok := schema["not"]; ok && len(s.validate(not, v, pointer, refs)) == 0 {
+		fail("value must not match the schema in not")
+	}
+
+	return errs
+}
+
+func (s *Schema) validateObject(schema map[string]interface{}, obj map[string]interface{}, pointer string) SchemaErrors {
+	var errs SchemaErrors
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			if field, ok := r.(string); ok {
+				if _, exists := obj[field]; !exists {
+					errs = append(errs, SchemaError{pointer, fmt.Sprintf("missing required field '%s'", field)})
+				}
+			}
+		}
+	}
+	if min, ok := schema["minProperties"].(float64); ok && float64(len(obj)) < min {
+		errs = append(errs, SchemaError{pointer, fmt.Sprintf("expected at least %v fields, got %d", min, len(obj))})
+	}
+	if max, ok := schema["maxProperties"].(float64); ok && float64(len(obj)) > max {
+		errs = append(errs, SchemaError{pointer, fmt.Sprintf("expected at most %v fields, got %d", max, len(obj))})
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	patternProperties, _ := schema["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPointer := pointer + "/" + escapePointer(k)
+		matched := false
+
+		if sub, ok := properties[k]; ok {
+			matched = true
+			errs = append(errs, s.validate(sub, obj[k], childPointer, nil)...)
+		}
+		for pattern, sub := range patternProperties {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				errs = append(errs, SchemaError{pointer, fmt.Sprintf("invalid pattern '%s'", pattern)})
+				continue
+			}
+			if re.MatchString(k) {
+				matched = true
+				errs = append(errs, s.validate(sub, obj[k], childPointer, nil)...)
+			}
+		}
+
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				errs = append(errs, SchemaError{childPointer, "additional field is not allowed"})
+			} else {
+				errs = append(errs, s.validate(additional, obj[k], childPointer, nil)...)
+			}
+		}
+	}
+
+	return errs
+}
+
+func (s *Schema) validateArray(schema map[string]interface{}, arr []interface{}, pointer string) SchemaErrors {
+	var errs SchemaErrors
+
+	if min, ok := schema["minItems"].(float64); ok && float64(len(arr)) < min {
+		errs = append(errs, SchemaError{pointer, fmt.Sprintf("expected at least %v items, got %d", min, len(arr))})
+	}
+	if max, ok := schema["maxItems"].(float64); ok && float64(len(arr)) > max {
+		errs = append(errs, SchemaError{pointer, fmt.Sprintf("expected at most %v items, got %d", max, len(arr))})
+	}
+	if unique, ok := schema["uniqueItems"].(bool); ok && unique {
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if reflect.DeepEqual(normalizeValue(arr[i]), normalizeValue(arr[j])) {
+					errs = append(errs, SchemaError{pointer, fmt.Sprintf("items %d and %d are equal", j, i)})
+				}
+			}
+		}
+	}
+
+	switch items := schema["items"].(type) {
+	case []interface{}:
+		for i := 0; i < len(items) && i < len(arr); i++ {
+			errs = append(errs, s.validate(items[i], arr[i], fmt.Sprintf("%s/%d", pointer, i), nil)...)
+		}
+	case nil:
+	default:
+		for i, item := range arr {
+			errs = append(errs, s.validate(items, item, fmt.Sprintf("%s/%d", pointer, i), nil)...)
+		}
+	}
+
+	return errs
+}
+
+func validateNumber(schema map[string]interface{}, n float64, pointer string) SchemaErrors {
+	var errs SchemaErrors
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, SchemaError{pointer, fmt.Sprintf(format, args...)})
+	}
+
+	if min, ok := schema["minimum"].(float64); ok && n < min {
+		fail("value %v is less than minimum %v", n, min)
+	}
+	if max, ok := schema["maximum"].(float64); ok && n > max {
+		fail("value %v is greater than maximum %v", n, max)
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+		fail("value %v is not greater than %v", n, min)
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+		fail("value %v is not less than %v", n, max)
+	}
+	if multiple, ok := schema["multipleOf"].(float64); ok && multiple > 0 {
+		if q := n / multiple; q != math.Trunc(q) {
+			fail("value %v is not a multiple of %v", n, multiple)
+		}
+	}
+
+	return errs
+}
+
+func validateString(schema map[string]interface{}, str string, pointer string) SchemaErrors {
+	var errs SchemaErrors
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, SchemaError{pointer, fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(str)
+	if min, ok := schema["minLength"].(float64); ok && float64(length) < min {
+		fail("expected at least %v characters, got %d", min, length)
+	}
+	if max, ok := schema["maxLength"].(float64); ok && float64(length) > max {
+		fail("expected at most %v characters, got %d", max, length)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fail("invalid pattern '%s'", pattern)
+		} else if !re.MatchString(str) {
+			fail("value '%s' does not match pattern '%s'", str, pattern)
+		}
+	}
+
+	return errs
+}
+
+// resolve returns the part of the schema referenced by a local reference (e.g. #/definitions/entity).
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only local references are supported, got '%s'", ref)
+	}
+	resolved, err := ResolvePointer(s.root, ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve reference '%s': %v", ref, err)
+	}
+	return resolved, nil
+}
+
+// ResolvePointer returns the value in doc referenced by the JSON pointer (RFC 6901), e.g. /data/0/templates.
+// An empty pointer references the whole document.
+func ResolvePointer(doc interface{}, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return doc, nil
+	} else if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("Invalid JSON pointer '%s'", pointer)
+	}
+
+	current := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+
+		switch val := current.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if current, ok = val[token]; !ok {
+				return nil, fmt.Errorf("Could not find field '%s' in JSON pointer '%s'", token, pointer)
+			}
+		case []interface{}:
+			var i int
+			if _, err := fmt.Sscanf(token, "%d", &i); err != nil || i < 0 || i >= len(val) || fmt.Sprint(i) != token {
+				return nil, fmt.Errorf("Invalid index '%s' in JSON pointer '%s'", token, pointer)
+			}
+			current = val[i]
+		default:
+			return nil, fmt.Errorf("Could not resolve '%s' in JSON pointer '%s'", token, pointer)
+		}
+	}
+	return current, nil
+}
+
+func escapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+// normalizeValue converts Go values (e.g. structs, ints or typed slices) to the types produced by
+// encoding/json, so values created in Go can be validated the same way as decoded JSON.
+func normalizeValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, bool, float64, string, map[string]interface{}, []interface{}:
+		return v
+	}
+
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint())
+	case reflect.Float32:
+		return val.Float()
+	case reflect.Bool:
+		return val.Bool()
+	case reflect.String:
+		return val.String()
+	case reflect.Slice, reflect.Array:
+		arr := make([]interface{}, val.Len())
+		for i := range arr {
+			arr[i] = val.Index(i).Interface()
+		}
+		return arr
+	case reflect.Map, reflect.Struct, reflect.Ptr:
+		if m, err := ToMap(v); err == nil {
+			return m
+		}
+	}
+	return v
+}
+
+func matchesType(v interface{}, typ interface{}) bool {
+	switch t := typ.(type) {
+	case string:
+		actual := jsonType(v)
+		return actual == t || (t == "number" && actual == "integer")
+	case []interface{}:
+		for _, single := range t {
+			if matchesType(v, single) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeString(typ interface{}) string {
+	if types, ok := typ.([]interface{}); ok {
+		strs := make([]string, len(types))
+		for i, t := range types {
+			strs[i] = fmt.Sprint(t)
+		}
+		return strings.Join(strs, " or ")
+	}
+	return fmt.Sprint(typ)
+}
+
+func jsonType(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return reflect.TypeOf(v).String()
+}
@@ -0,0 +1,117 @@
+package chew
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := LoadSchema("test/data/test_schema.json")
+	assert.NoError(t, err)
+
+	valid := Chewable{
+		Global: map[string]interface{}{"version": 1},
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"table": "users.sql"},
+				Local:     map[string]interface{}{"name": "users", "kind": "table"},
+			},
+		},
+	}
+	assert.NoError(t, schema.Validate(valid))
+
+	invalid := Chewable{
+		Global: map[string]interface{}{"version": 1.5},
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"table": "users.sql"},
+				Local:     map[string]interface{}{"name": "users"},
+			},
+			{
+				Templates: map[string]string{"table": "Orders.sql"},
+				Local:     map[string]interface{}{"name": "Orders", "kind": "index", "tempaltes": "typo"},
+			},
+		},
+	}
+	err = schema.Validate(invalid)
+	assert.Equal(t, SchemaErrors{
+		{"/data/1/kind", "value is not one of [table view]"},
+		{"/data/1/name", "value 'Orders' does not match pattern '^[a-z_]+$'"},
+		{"/data/1/tempaltes", "additional field is not allowed"},
+		{"/version", "expected integer, got number"},
+	}, err)
+}
+
+func TestSchema_ValidateValue(t *testing.T) {
+	testCases := []struct {
+		Schema string
+		Value  interface{}
+		Errors SchemaErrors
+	}{
+		{`true`, "anything", nil},
+		{`false`, "anything", SchemaErrors{{"", "no value is allowed"}}},
+		{`{"type":["string","null"]}`, nil, nil},
+		{`{"type":"number"}`, 3, nil},
+		{`{"type":"string","minLength":2}`, "a", SchemaErrors{{"", "expected at least 2 characters, got 1"}}},
+		{`{"items":{"type":"string"},"maxItems":1}`, []string{"a", "b"}, SchemaErrors{{"", "expected at most 1 items, got 2"}}},
+		{`{"uniqueItems":true}`, []interface{}{1, 2, 1}, SchemaErrors{{"", "items 0 and 2 are equal"}}},
+		{`{"anyOf":[{"type":"string"},{"type":"boolean"}]}`, 1, SchemaErrors{{"", "value does not match any schema in anyOf"}}},
+		{`{"oneOf":[{"minimum":1},{"maximum":5}]}`, 3, SchemaErrors{{"", "value matches 2 schemas in oneOf instead of exactly one"}}},
+		{`{"not":{"type":"object"}}`, map[string]interface{}{}, SchemaErrors{{"", "value must not match the schema in not"}}},
+		{`{"patternProperties":{"^x-":{"type":"string"}},"additionalProperties":false}`,
+			map[string]interface{}{"x-a/b": 1, "y": 2},
+			SchemaErrors{{"/x-a~1b", "expected string, got integer"}, {"/y", "additional field is not allowed"}}},
+		{`{"$ref":"#"}`, 1, SchemaErrors{{"", "reference cycle detected: #"}}},
+		{`{"definitions":{"a":{"$ref":"#/definitions/b"},"b":{"allOf":[{"$ref":"#/definitions/a"}]}},"$ref":"#/definitions/a"}`,
+			1, SchemaErrors{{"", "reference cycle detected: #/definitions/a"}}},
+		{`{"definitions":{"node":{"type":"object","properties":{"child":{"$ref":"#/definitions/node"}}}},"$ref":"#/definitions/node"}`,
+			map[string]interface{}{"child": map[string]interface{}{"child": map[string]interface{}{}}}, nil},
+		{`{"$ref":"#/definitions/missing"}`, 1, SchemaErrors{{"", "could not resolve reference '#/definitions/missing': Could not find field 'definitions' in JSON pointer '/definitions/missing'"}}},
+	}
+
+	for _, tc := range testCases {
+		schema, err := ParseSchema([]byte(tc.Schema))
+		assert.NoError(t, err, tc.Schema)
+
+		err = schema.ValidateValue(tc.Value)
+		if tc.Errors == nil {
+			assert.NoError(t, err, tc.Schema)
+		} else {
+			assert.Equal(t, tc.Errors, err, tc.Schema)
+		}
+	}
+}
+
+func TestResolvePointer(t *testing.T) {
+	doc := map[string]interface{}{
+		"a/b": map[string]interface{}{
+			"list": []interface{}{"x", "y"},
+		},
+		"m~n": 1,
+	}
+
+	testCases := []struct {
+		Pointer  string
+		Expected interface{}
+		Error    bool
+	}{
+		{"", doc, false},
+		{"/m~0n", 1, false},
+		{"/a~1b/list/1", "y", false},
+		{"/a~1b/list/01", nil, true},
+		{"/a~1b/list/2", nil, true},
+		{"/missing", nil, true},
+		{"missing", nil, true},
+	}
+
+	for _, tc := range testCases {
+		actual, err := ResolvePointer(doc, tc.Pointer)
+		if tc.Error {
+			assert.Error(t, err, tc.Pointer)
+		} else {
+			assert.NoError(t, err, tc.Pointer)
+			assert.Equal(t, tc.Expected, actual, tc.Pointer)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+// Package gosource parses the Go source files of a package and provides its types as data for Chew, e.g. to
+// generate mocks, builders or String methods.
+//
+// The package is stored in the global field 'package' and the selected types in the global field 'types':
+//
+//	{
+//	  "package": {"name": "model", "dir": "/src/model", "doc": "Package model ..."},
+//	  "types": [
+//	    {
+//	      "name": "User", "kind": "struct", "exported": true, "doc": "User is ...", "markers": ["builder"],
+//	      "fields": [{"name": "ID", "type": "int64", "qualified_type": "int64", "tag": "json:\"id\"", "tags": {"json": "id"}, "embedded": false, ...}],
+//	      "methods": [{"name": "String", "receiver": "User", "pointer": false, "params": [], "results": [{"name": "", "type": "string", "qualified_type": "string"}], ...}]
+//	    }
+//	  ]
+//	}
+//
+// Types are selected by name patterns (e.g. "*Service") or by marker comments in their doc comment, e.g.
+//
+//	//chew:builder
+//	type User struct { ... }
+//
+// Test files and files excluded by build constraints are skipped. Types are represented as they are written in
+// the source (e.g. "*time.Time") in the field 'type'. The package is also type-checked with go/types, the field
+// 'qualified_type' contains the resolved type, where aliases are followed and types from other packages are
+// qualified with their import path (e.g. "*github.com/shop/model.Timestamp"). Imported packages are read from
+// source, types which can't be resolved (e.g. because a dependency is missing) have an empty 'qualified_type'.
+package gosource
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lovromazgon/chew"
+)
+
+// DefaultMarker is the prefix of marker comments.
+const DefaultMarker = "chew:"
+
+// Options define which types are selected and which templates are executed for them.
+type Options struct {
+	// Patterns select types by name (with the syntax of path.Match, e.g. "*Service")
+	Patterns []string
+	// Marked selects types with at least one marker comment
+	Marked bool
+	// Marker is the prefix of marker comments, DefaultMarker is used if empty
+	Marker string
+	// Templates are executed for every selected type, if not empty
+	Templates map[string]string
+}
+
+// Package is a parsed Go package.
+type Package struct {
+	Name  string `json:"name"`
+	Dir   string `json:"dir"`
+	Doc   string `json:"doc"`
+	Types []Type `json:"types"`
+}
+
+// Type is a named type declared in the package. Kind is "struct", "interface" or "other", Underlying
+// contains the source of the underlying type for other kinds (e.g. "[]string"). Markers contains the text
+// after the marker prefix of every marker comment.
+type Type struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	Exported   bool     `json:"exported"`
+	Doc        string   `json:"doc"`
+	Markers    []string `json:"markers"`
+	TypeParams []Param  `json:"type_params"`
+	Underlying string   `json:"underlying"`
+	Fields     []Field  `json:"fields"`
+	Embeds     []string `json:"embeds"`
+	Methods    []Method `json:"methods"`
+}
+
+// Field is a field of a struct. Tag contains the raw tag, Tags the parsed key-value pairs.
+type Field struct {
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	QualifiedType string            `json:"qualified_type"`
+	Exported      bool              `json:"exported"`
+	Embedded      bool              `json:"embedded"`
+	Tag           string            `json:"tag"`
+	Tags          map[string]string `json:"tags"`
+	Doc           string            `json:"doc"`
+}
+
+// Method is a method of a type, or a method in an interface (Receiver is empty in this case).
+type Method struct {
+	Name     string  `json:"name"`
+	Receiver string  `json:"receiver"`
+	Pointer  bool    `json:"pointer"`
+	Exported bool    `json:"exported"`
+	Doc      string  `json:"doc"`
+	Params   []Param `json:"params"`
+	Results  []Param `json:"results"`
+	Variadic bool    `json:"variadic"`
+}
+
+// Param is a parameter or result of a method, or a type parameter. Name is empty for unnamed parameters.
+type Param struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	QualifiedType string `json:"qualified_type"`
+}
+
+// Load parses the package in the directory and returns its selected types as a Chewable. If Options.Templates
+// is not empty, one ChewableData which executes the templates is created for every selected type.
+func Load(dir string, opts Options) (*chew.Chewable, error) {
+	pkg, err := ParseDir(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	global, err := globalMap(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	chewable := &chew.Chewable{Global: global}
+	if len(opts.Templates) > 0 {
+		if chewable.Data, err = chew.NewEntries(pkg.Types, opts.Templates); err != nil {
+			return nil, err
+		}
+	}
+	return chewable, nil
+}
+
+// Decoder is a chew.FileDecoder for Go source files. It can be registered for the extension ".go", in which
+// case the whole package in the folder of the data file is parsed.
+type Decoder struct {
+	Options Options
+}
+
+// DecodeFile parses the package in the folder of the file. The field 'data' is always defined, it is empty
+// if Options.Templates is empty.
+func (d *Decoder) DecodeFile(path string) (interface{}, error) {
+	chewable, err := Load(filepath.Dir(path), d.Options)
+	if err != nil {
+		return nil, err
+	}
+	return chewable.ToDoc(), nil
+}
+
+// Decode is not supported, because a package consists of multiple files.
+func (d *Decoder) Decode(data []byte) (interface{}, error) {
+	return nil, errors.New("Go source can only be decoded from a file")
+}
+
+// globalMap returns the global data of the package, where the package is stored in the field 'package'
+// (without the types) and the types in the field 'types'.
+func globalMap(pkg *Package) (map[string]interface{}, error) {
+	pkgMap, err := chew.ToMap(pkg)
+	if err != nil {
+		return nil, err
+	}
+	typeList := pkgMap["types"]
+	delete(pkgMap, "types")
+
+	return map[string]interface{}{
+		"package": pkgMap,
+		"types":   typeList,
+	}, nil
+}
+
+// ParseDir parses the Go files (without tests) in the directory and returns the package with the
+// selected types, sorted by name.
+func ParseDir(dir string, opts Options) (*Package, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(absDir)
+	if err != nil {
+		return nil, err
+	}
+
+	marker := opts.Marker
+	if marker == "" {
+		marker = DefaultMarker
+	}
+
+	fset := token.NewFileSet()
+	pkg := &Package{Dir: absDir, Types: []Type{}}
+	var astFiles []*ast.File
+
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if match, err := build.Default.MatchFile(absDir, name); err != nil || !match {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(absDir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if pkg.Name == "" {
+			pkg.Name = f.Name.Name
+		} else if pkg.Name != f.Name.Name {
+			return nil, fmt.Errorf("Found packages %s and %s in %s", pkg.Name, f.Name.Name, absDir)
+		}
+		if f.Doc != nil {
+			pkg.Doc = strings.TrimSpace(f.Doc.Text())
+		}
+		astFiles = append(astFiles, f)
+	}
+
+	r := check(fset, pkg.Name, astFiles)
+	typesByName := make(map[string]*Type)
+	var methods []Method
+
+	for _, f := range astFiles {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				if d.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range d.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					docGroup := typeSpec.Doc
+					if docGroup == nil && len(d.Specs) == 1 {
+						docGroup = d.Doc
+					}
+					typ := r.newType(typeSpec, docGroup, marker)
+					typesByName[typ.Name] = &typ
+				}
+			case *ast.FuncDecl:
+				if d.Recv != nil && len(d.Recv.List) == 1 {
+					methods = append(methods, r.newMethod(d))
+				}
+			}
+		}
+	}
+
+	for _, method := range methods {
+		if typ, ok := typesByName[method.Receiver]; ok {
+			typ.Methods = append(typ.Methods, method)
+		}
+	}
+
+	names := make([]string, 0, len(typesByName))
+	for name := range typesByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		typ := typesByName[name]
+		selected, err := opts.selects(typ)
+		if err != nil {
+			return nil, err
+		}
+		if selected {
+			pkg.Types = append(pkg.Types, *typ)
+		}
+	}
+	return pkg, nil
+}
+
+// selects returns true if the type is selected by the options.
+func (opts Options) selects(typ *Type) (bool, error) {
+	if len(opts.Patterns) == 0 && !opts.Marked {
+		return true, nil
+	}
+	if opts.Marked && len(typ.Markers) > 0 {
+		return true, nil
+	}
+	for _, pattern := range opts.Patterns {
+		matched, err := path.Match(pattern, typ.Name)
+		if err != nil {
+			return false, fmt.Errorf("Invalid type pattern '%s': %v", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// resolver resolves the types of expressions with the information collected while type-checking the package.
+type resolver struct {
+	pkg  *types.Package
+	info *types.Info
+}
+
+// check type-checks the package. Errors are ignored, the information about the expressions which could be
+// resolved is collected anyway.
+func check(fset *token.FileSet, name string, files []*ast.File) *resolver {
+	r := &resolver{info: &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	r.pkg, _ = conf.Check(name, fset, files, r.info)
+	return r
+}
+
+// qualifiedType returns the resolved type of the expression, where aliases are followed and types from other
+// packages are qualified with their import path. An empty string is returned if the type can't be resolved.
+func (r *resolver) qualifiedType(expr ast.Expr) string {
+	if ellipsis, ok := expr.(*ast.Ellipsis); ok {
+		elem := r.qualifiedType(ellipsis.Elt)
+		if elem == "" {
+			return ""
+		}
+		return "..." + elem
+	}
+
+	typ := r.info.TypeOf(expr)
+	if typ == nil || !resolved(typ) {
+		return ""
+	}
+	return types.TypeString(unalias(typ), func(pkg *types.Package) string {
+		if pkg == r.pkg {
+			return ""
+		}
+		return pkg.Path()
+	})
+}
+
+// resolved returns false if the type or one of its element types is invalid.
+func resolved(typ types.Type) bool {
+	switch t := types.Unalias(typ).(type) {
+	case *types.Basic:
+		return t.Kind() != types.Invalid
+	case *types.Pointer:
+		return resolved(t.Elem())
+	case *types.Slice:
+		return resolved(t.Elem())
+	case *types.Array:
+		return resolved(t.Elem())
+	case *types.Chan:
+		return resolved(t.Elem())
+	case *types.Map:
+		return resolved(t.Key()) && resolved(t.Elem())
+	}
+	return true
+}
+
+// unalias follows aliases in the type and in the element types of pointers, slices, arrays, channels and maps.
+func unalias(typ types.Type) types.Type {
+	switch t := types.Unalias(typ).(type) {
+	case *types.Pointer:
+		return types.NewPointer(unalias(t.Elem()))
+	case *types.Slice:
+		return types.NewSlice(unalias(t.Elem()))
+	case *types.Array:
+		return types.NewArray(unalias(t.Elem()), t.Len())
+	case *types.Chan:
+		return types.NewChan(t.Dir(), unalias(t.Elem()))
+	case *types.Map:
+		return types.NewMap(unalias(t.Key()), unalias(t.Elem()))
+	default:
+		return t
+	}
+}
+
+func (r *resolver) newType(spec *ast.TypeSpec, doc *ast.CommentGroup, marker string) Type {
+	typ := Type{
+		Name:       spec.Name.Name,
+		Exported:   ast.IsExported(spec.Name.Name),
+		Doc:        commentText(doc),
+		Markers:    markers(doc, marker),
+		TypeParams: r.params(spec.TypeParams),
+		Fields:     []Field{},
+		Embeds:     []string{},
+		Methods:    []Method{},
+	}
+
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		typ.Kind = "struct"
+		for _, field := range t.Fields.List {
+			typ.Fields = append(typ.Fields, r.newFields(field)...)
+		}
+	case *ast.InterfaceType:
+		typ.Kind = "interface"
+		for _, field := range t.Methods.List {
+			funcType, ok := field.Type.(*ast.FuncType)
+			if !ok {
+				typ.Embeds = append(typ.Embeds, types.ExprString(field.Type))
+				continue
+			}
+			for _, name := range field.Names {
+				method := Method{
+					Name:     name.Name,
+					Exported: ast.IsExported(name.Name),
+					Doc:      commentText(field.Doc),
+				}
+				r.setSignature(&method, funcType)
+				typ.Methods = append(typ.Methods, method)
+			}
+		}
+	default:
+		typ.Kind = "other"
+		typ.Underlying = types.ExprString(spec.Type)
+	}
+	return typ
+}
+
+func (r *resolver) newFields(field *ast.Field) []Field {
+	doc := commentText(field.Doc)
+	if doc == "" {
+		doc = commentText(field.Comment)
+	}
+
+	f := Field{
+		Type:          types.ExprString(field.Type),
+		QualifiedType: r.qualifiedType(field.Type),
+		Tags:          map[string]string{},
+		Doc:           doc,
+	}
+	if field.Tag != nil {
+		if tag, err := strconv.Unquote(field.Tag.Value); err == nil {
+			f.Tag = tag
+			f.Tags = parseTag(tag)
+		}
+	}
+
+	if len(field.Names) == 0 {
+		// embedded field, the name is the name of the type without the pointer and the package
+		name := strings.TrimPrefix(f.Type, "*")
+		if i := strings.Index(name, "["); i >= 0 {
+			name = name[:i]
+		}
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		f.Name = name
+		f.Embedded = true
+		f.Exported = ast.IsExported(name)
+		return []Field{f}
+	}
+
+	fields := make([]Field, len(field.Names))
+	for i, name := range field.Names {
+		fields[i] = f
+		fields[i].Name = name.Name
+		fields[i].Exported = ast.IsExported(name.Name)
+	}
+	return fields
+}
+
+func (r *resolver) newMethod(decl *ast.FuncDecl) Method {
+	method := Method{
+		Name:     decl.Name.Name,
+		Exported: ast.IsExported(decl.Name.Name),
+		Doc:      commentText(decl.Doc),
+	}
+
+	recv := decl.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		method.Pointer = true
+		recv = star.X
+	}
+	// strip type parameters of generic receivers
+	switch r := recv.(type) {
+	case *ast.IndexExpr:
+		recv = r.X
+	case *ast.IndexListExpr:
+		recv = r.X
+	}
+	method.Receiver = types.ExprString(recv)
+
+	r.setSignature(&method, decl.Type)
+	return method
+}
+
+func (r *resolver) setSignature(m *Method, funcType *ast.FuncType) {
+	m.Params = r.params(funcType.Params)
+	m.Results = r.params(funcType.Results)
+	if n := len(funcType.Params.List); n > 0 {
+		_, m.Variadic = funcType.Params.List[n-1].Type.(*ast.Ellipsis)
+	}
+}
+
+func (r *resolver) params(list *ast.FieldList) []Param {
+	params := []Param{}
+	if list == nil {
+		return params
+	}
+	for _, field := range list.List {
+		typ, qualified := types.ExprString(field.Type), r.qualifiedType(field.Type)
+		if len(field.Names) == 0 {
+			params = append(params, Param{Type: typ, QualifiedType: qualified})
+		}
+		for _, name := range field.Names {
+			params = append(params, Param{Name: name.Name, Type: typ, QualifiedType: qualified})
+		}
+	}
+	return params
+}
+
+// commentText returns the text of the comment without markers and directives.
+func commentText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Text())
+}
+
+// markers returns the text after the marker prefix of all comment lines which start with the prefix.
+func markers(doc *ast.CommentGroup, marker string) []string {
+	markers := []string{}
+	if doc == nil {
+		return markers
+	}
+	for _, comment := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if strings.HasPrefix(text, marker) {
+			markers = append(markers, strings.TrimSpace(strings.TrimPrefix(text, marker)))
+		}
+	}
+	return markers
+}
+
+// parseTag parses a struct tag in the conventional format key:"value" key2:"value2".
+func parseTag(tag string) map[string]string {
+	tags := make(map[string]string)
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		i := strings.Index(tag, ":\"")
+		if i <= 0 {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		value, err := strconv.QuotedPrefix(tag)
+		if err != nil {
+			break
+		}
+		tag = tag[len(value):]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			tags[key] = unquoted
+		}
+	}
+	return tags
+}
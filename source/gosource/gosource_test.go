@@ -0,0 +1,84 @@
+package gosource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDir(t *testing.T) {
+	pkg, err := ParseDir("testdata/model", Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "model", pkg.Name)
+	assert.Equal(t, "Package model contains the test model.", pkg.Doc)
+
+	var names []string
+	for _, typ := range pkg.Types {
+		names = append(names, typ.Name)
+	}
+	assert.Equal(t, []string{"Base", "Set", "Status", "Timestamp", "User", "UserService", "fmt_Stringer"}, names)
+
+	user := pkg.Types[4]
+	assert.Equal(t, "struct", user.Kind)
+	assert.Equal(t, "User is a registered user.", user.Doc)
+	assert.Equal(t, []string{"builder", "stringer"}, user.Markers)
+	assert.Equal(t, []Field{
+		{Name: "Base", Type: "Base", QualifiedType: "Base", Exported: true, Embedded: true, Tags: map[string]string{}},
+		{Name: "Name", Type: "string", QualifiedType: "string", Exported: true, Tag: `json:"name"`, Tags: map[string]string{"json": "name"}},
+		{Name: "Email", Type: "string", QualifiedType: "string", Exported: true, Tag: `json:"name"`, Tags: map[string]string{"json": "name"}},
+		{Name: "Roles", Type: "[]string", QualifiedType: "[]string", Exported: true, Tags: map[string]string{}, Doc: "Roles of the user"},
+		{Name: "admin", Type: "bool", QualifiedType: "bool", Tags: map[string]string{}, Doc: "only set internally"},
+	}, user.Fields)
+	assert.Equal(t, []Method{
+		{
+			Name: "String", Receiver: "User", Exported: true, Doc: "String returns the name of the user.",
+			Params: []Param{}, Results: []Param{{Type: "string", QualifiedType: "string"}},
+		},
+		{
+			Name: "SetRoles", Receiver: "User", Pointer: true, Exported: true, Doc: "SetRoles replaces the roles.",
+			Params: []Param{{Name: "roles", Type: "...string", QualifiedType: "...string"}}, Results: []Param{}, Variadic: true,
+		},
+	}, user.Methods)
+
+	base := pkg.Types[0]
+	assert.Equal(t, map[string]string{"json": "id", "db": "id"}, base.Fields[0].Tags)
+	assert.Equal(t, "time.Time", base.Fields[1].Type)
+	assert.Equal(t, "time.Time", base.Fields[1].QualifiedType)
+	// aliases are followed
+	assert.Equal(t, "*Timestamp", base.Fields[2].Type)
+	assert.Equal(t, "*time.Time", base.Fields[2].QualifiedType)
+	// types which can't be resolved
+	assert.Equal(t, "Undefined", base.Fields[3].Type)
+	assert.Equal(t, "", base.Fields[3].QualifiedType)
+
+	service := pkg.Types[5]
+	assert.Equal(t, "interface", service.Kind)
+	assert.Equal(t, []string{"fmt_Stringer"}, service.Embeds)
+	assert.Equal(t, []Param{{Name: "id", Type: "int64", QualifiedType: "int64"}}, service.Methods[0].Params)
+	assert.Equal(t, []Param{{Type: "*User", QualifiedType: "*User"}, {Type: "error", QualifiedType: "error"}}, service.Methods[0].Results)
+
+	set := pkg.Types[1]
+	assert.Equal(t, "other", set.Kind)
+	assert.Equal(t, "map[T]struct{}", set.Underlying)
+	assert.Equal(t, []Param{{Name: "T", Type: "comparable", QualifiedType: "comparable"}}, set.TypeParams)
+}
+
+func TestParseDir_Select(t *testing.T) {
+	pkg, err := ParseDir("testdata/model", Options{Patterns: []string{"*Service"}, Marked: true})
+	assert.NoError(t, err)
+	assert.Len(t, pkg.Types, 2)
+	assert.Equal(t, "User", pkg.Types[0].Name)
+	assert.Equal(t, "UserService", pkg.Types[1].Name)
+
+	_, err = ParseDir("testdata/model", Options{Patterns: []string{"["}})
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	chewable, err := Load("testdata/model", Options{Marked: true, Templates: map[string]string{"builder": "{{ .name }}_builder.go"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "model", chewable.Global["package"].(map[string]interface{})["name"])
+	assert.Len(t, chewable.Data, 1)
+	assert.Equal(t, "User", chewable.Data[0].Local["name"])
+	assert.Equal(t, []string{"builder", "stringer"}, chewable.Data[0].Local["markers"])
+}
@@ -0,0 +1,51 @@
+// Package model contains the test model.
+package model
+
+import "time"
+
+// Base contains common fields.
+type Base struct {
+	ID      int64     `json:"id" db:"id"`
+	Created time.Time `json:"created"`
+	Updated *Timestamp
+	Deleted Undefined
+}
+
+// Timestamp is an alias of time.Time.
+type Timestamp = time.Time
+
+// User is a registered user.
+//
+//chew:builder
+//chew:stringer
+type User struct {
+	Base
+	Name, Email string `json:"name"`
+	// Roles of the user
+	Roles []string
+	admin bool // only set internally
+}
+
+// String returns the name of the user.
+func (u User) String() string {
+	return u.Name
+}
+
+// SetRoles replaces the roles.
+func (u *User) SetRoles(roles ...string) {
+	u.Roles = roles
+}
+
+// UserService manages users.
+type UserService interface {
+	Get(id int64) (*User, error)
+	fmt_Stringer
+}
+
+type fmt_Stringer interface {
+	String() string
+}
+
+type Status int
+
+type Set[T comparable] map[T]struct{}
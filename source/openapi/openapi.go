@@ -0,0 +1,540 @@
+// Package openapi loads OpenAPI 3 documents (YAML or JSON) and provides their operations and schemas as data
+// for Chew, e.g. to generate API clients and handlers.
+//
+// All references ($ref) are resolved, also references into other files relative to the document. A schema
+// which is referenced from components/schemas contains its name in the field 'ref', so templates can use
+// named types. Recursive references are not resolved, instead they are replaced by {"ref": "Name"}.
+//
+// The document is normalized into the following global fields:
+//
+//	{
+//	  "api": {"title": "Pet Store", "version": "1.0.0", "description": "", "servers": ["https://example.com/v1"]},
+//	  "operations": [
+//	    {
+//	      "id": "getPet", "method": "GET", "path": "/pets/{id}", "summary": "", "description": "", "tags": ["pets"], "deprecated": false,
+//	      "parameters": [{"name": "id", "in": "path", "required": true, "description": "", "schema": {"type": "integer"}}],
+//	      "request_body": null,
+//	      "responses": [{"status": "200", "description": "", "content_type": "application/json", "schema": {"ref": "Pet", ...}}]
+//	    }
+//	  ],
+//	  "schemas": [{"name": "Pet", "schema": {"type": "object", "properties": {...}}}]
+//	}
+//
+// Optionally one data object is created for every operation and/or every schema.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lovromazgon/chew"
+)
+
+var (
+	methods       = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+	schemaPointer = regexp.MustCompile(`^/components/schemas/([^/]+)$`)
+	nonAlnum      = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// Options define which templates are executed for operations and schemas.
+type Options struct {
+	// OperationTemplates are executed for every operation, if not empty
+	OperationTemplates map[string]string
+	// SchemaTemplates are executed for every schema in components/schemas, if not empty
+	SchemaTemplates map[string]string
+}
+
+// API is a normalized OpenAPI document.
+type API struct {
+	Title       string      `json:"title"`
+	Version     string      `json:"version"`
+	Description string      `json:"description"`
+	Servers     []string    `json:"servers"`
+	Operations  []Operation `json:"operations"`
+	Schemas     []Schema    `json:"schemas"`
+}
+
+// Operation is an operation on a path. If the operation has no operationId, ID is generated from the method
+// and the path (e.g. getUsersId for GET /users/{id}). Parameters contain the parameters of the path and
+// the operation.
+type Operation struct {
+	ID          string      `json:"id"`
+	Method      string      `json:"method"`
+	Path        string      `json:"path"`
+	Summary     string      `json:"summary"`
+	Description string      `json:"description"`
+	Tags        []string    `json:"tags"`
+	Deprecated  bool        `json:"deprecated"`
+	Parameters  []Parameter `json:"parameters"`
+	RequestBody *Body       `json:"request_body"`
+	Responses   []Response  `json:"responses"`
+}
+
+// Parameter is a parameter of an operation.
+type Parameter struct {
+	Name        string                 `json:"name"`
+	In          string                 `json:"in"`
+	Required    bool                   `json:"required"`
+	Description string                 `json:"description"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+// Body is the request body of an operation. If the body defines multiple media types, application/json is
+// preferred, otherwise the first media type (sorted by name) is used.
+type Body struct {
+	Required    bool                   `json:"required"`
+	Description string                 `json:"description"`
+	ContentType string                 `json:"content_type"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+// Response is a response of an operation. Status is the status code or "default".
+type Response struct {
+	Status      string                 `json:"status"`
+	Description string                 `json:"description"`
+	ContentType string                 `json:"content_type"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+// Schema is a named schema from components/schemas.
+type Schema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// Decoder is a chew.FileDecoder for OpenAPI documents. Ordinary JSON and YAML files can contain a field 'openapi'
+// too, so the decoder should not be registered for their extensions. Register it for a dedicated extension
+// instead and select it with chew.LoadOptions.Format, e.g.:
+//
+//	chew.RegisterDecoder(".openapi", &openapi.Decoder{})
+//	chewable, err := chew.LoadChewableWithOptions("api.yaml", chew.LoadOptions{Format: "openapi"})
+//
+// The field 'data' is always defined, it is empty if no templates are defined in Options.
+type Decoder struct {
+	Options Options
+}
+
+// DecodeFile reads the document with the provided path.
+func (d *Decoder) DecodeFile(path string) (interface{}, error) {
+	api, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return d.toDoc(api)
+}
+
+// Decode decodes the document. References into other files are resolved relative to the working directory.
+func (d *Decoder) Decode(data []byte) (interface{}, error) {
+	doc, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if !isOpenAPI(doc) {
+		return nil, errors.New("Data is not an OpenAPI document, the field 'openapi' is missing")
+	}
+
+	api, err := normalize(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	return d.toDoc(api)
+}
+
+func (d *Decoder) toDoc(api *API) (interface{}, error) {
+	chewable, err := newChewable(api, d.Options)
+	if err != nil {
+		return nil, err
+	}
+	return chewable.ToDoc(), nil
+}
+
+// Load reads the OpenAPI document with the provided path and returns it as a Chewable.
+func Load(path string, opts Options) (*chew.Chewable, error) {
+	api, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newChewable(api, opts)
+}
+
+// ReadFile reads the OpenAPI document with the provided path, resolves all references and normalizes it.
+func ReadFile(path string) (*API, error) {
+	doc, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isOpenAPI(doc) {
+		return nil, fmt.Errorf("%s is not an OpenAPI document, the field 'openapi' is missing", path)
+	}
+	return normalize(doc, path)
+}
+
+func newChewable(api *API, opts Options) (*chew.Chewable, error) {
+	global, err := chew.ToMap(api)
+	if err != nil {
+		return nil, err
+	}
+
+	chewable := &chew.Chewable{
+		Global: map[string]interface{}{
+			"api": map[string]interface{}{
+				"title":       global["title"],
+				"version":     global["version"],
+				"description": global["description"],
+				"servers":     global["servers"],
+			},
+			"operations": global["operations"],
+			"schemas":    global["schemas"],
+		},
+	}
+
+	if len(opts.OperationTemplates) > 0 {
+		entries, err := chew.NewEntries(api.Operations, opts.OperationTemplates)
+		if err != nil {
+			return nil, err
+		}
+		chewable.Data = append(chewable.Data, entries...)
+	}
+	if len(opts.SchemaTemplates) > 0 {
+		entries, err := chew.NewEntries(api.Schemas, opts.SchemaTemplates)
+		if err != nil {
+			return nil, err
+		}
+		chewable.Data = append(chewable.Data, entries...)
+	}
+	return chewable, nil
+}
+
+func isOpenAPI(doc interface{}) bool {
+	docMap, ok := doc.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = docMap["openapi"]
+	return ok
+}
+
+// readFile decodes the file, JSON files are decoded with encoding/json, other files as YAML.
+func readFile(path string) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		var doc interface{}
+		err := json.Unmarshal(data, &doc)
+		return doc, err
+	}
+	return decode(data)
+}
+
+// decode decodes YAML (or JSON) into the same tree encoding/json produces.
+func decode(data []byte) (interface{}, error) {
+	return (&chew.YAMLDecoder{}).Decode(data)
+}
+
+// resolver resolves references in OpenAPI documents.
+type resolver struct {
+	files map[string]interface{}
+	stack []string
+}
+
+// resolve returns a copy of node in which all references are replaced. file is the path of the file
+// which contains node, doc its decoded content.
+func (r *resolver) resolve(node interface{}, file string, doc interface{}) (interface{}, error) {
+	switch val := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := val["$ref"].(string); ok {
+			return r.resolveRef(val, ref, file, doc)
+		}
+
+		resolved := make(map[string]interface{}, len(val))
+		for _, k := range sortedKeys(val) {
+			res, err := r.resolve(val[k], file, doc)
+			if err != nil {
+				return nil, err
+			}
+			resolved[k] = res
+		}
+		return resolved, nil
+
+	case []interface{}:
+		resolved := make([]interface{}, len(val))
+		for i, v := range val {
+			res, err := r.resolve(v, file, doc)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = res
+		}
+		return resolved, nil
+	}
+	return node, nil
+}
+
+func (r *resolver) resolveRef(node map[string]interface{}, ref, file string, doc interface{}) (interface{}, error) {
+	refFile, pointer := ref, ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		refFile, pointer = ref[:i], ref[i+1:]
+	}
+	pointer, err := url.PathUnescape(pointer)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid reference '%s': %v", ref, err)
+	}
+
+	// the target is resolved in the referenced document, the fields next to $ref in the current one
+	refPath, refDoc := file, doc
+	if refFile != "" {
+		if !filepath.IsAbs(refFile) && file != "" {
+			refFile = filepath.Join(filepath.Dir(file), refFile)
+		}
+		if refPath, err = filepath.Abs(refFile); err != nil {
+			return nil, err
+		}
+		if refDoc, err = r.read(refPath); err != nil {
+			return nil, fmt.Errorf("Could not resolve reference '%s': %v", ref, err)
+		}
+	}
+
+	name := ""
+	if match := schemaPointer.FindStringSubmatch(pointer); match != nil {
+		name = match[1]
+	}
+
+	key := refPath + "#" + pointer
+	for _, k := range r.stack {
+		if k == key {
+			if name == "" {
+				return nil, fmt.Errorf("Reference cycle detected: %s", ref)
+			}
+			// recursive schemas are referenced by name only
+			return map[string]interface{}{"ref": name}, nil
+		}
+	}
+
+	target, err := chew.ResolvePointer(refDoc, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("Could not resolve reference '%s': %v", ref, err)
+	}
+
+	r.stack = append(r.stack, key)
+	resolved, err := r.resolve(target, refPath, refDoc)
+	r.stack = r.stack[:len(r.stack)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedMap, ok := resolved.(map[string]interface{})
+	if !ok {
+		return resolved, nil
+	}
+	// fields next to $ref (e.g. description) override the referenced fields
+	for k, v := range node {
+		if k == "$ref" {
+			continue
+		}
+		if resolvedMap[k], err = r.resolve(v, file, doc); err != nil {
+			return nil, err
+		}
+	}
+	if name != "" {
+		resolvedMap["ref"] = name
+	}
+	return resolvedMap, nil
+}
+
+func (r *resolver) read(path string) (interface{}, error) {
+	if doc, ok := r.files[path]; ok {
+		return doc, nil
+	}
+	doc, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r.files[path] = doc
+	return doc, nil
+}
+
+// normalize resolves the references in the document and converts it to an API.
+func normalize(doc interface{}, path string) (*API, error) {
+	if path != "" {
+		var err error
+		if path, err = filepath.Abs(path); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &resolver{files: map[string]interface{}{path: doc}}
+	resolved, err := r.resolve(doc, path, doc)
+	if err != nil {
+		return nil, err
+	}
+	root := resolved.(map[string]interface{})
+
+	info := object(root["info"])
+	api := &API{
+		Title:       str(info["title"]),
+		Version:     str(info["version"]),
+		Description: str(info["description"]),
+		Servers:     []string{},
+		Operations:  []Operation{},
+		Schemas:     []Schema{},
+	}
+
+	for _, server := range list(root["servers"]) {
+		api.Servers = append(api.Servers, str(object(server)["url"]))
+	}
+
+	paths := object(root["paths"])
+	for _, p := range sortedKeys(paths) {
+		pathItem := object(paths[p])
+		for _, method := range methods {
+			op, ok := pathItem[method]
+			if !ok {
+				continue
+			}
+			operation, err := newOperation(p, method, object(op), list(pathItem["parameters"]))
+			if err != nil {
+				return nil, err
+			}
+			api.Operations = append(api.Operations, operation)
+		}
+	}
+
+	// schemas are resolved through a reference, so recursive references to the schema itself are detected
+	schemas := object(object(doc.(map[string]interface{})["components"])["schemas"])
+	for _, name := range sortedKeys(schemas) {
+		pointer := "#/components/schemas/" + strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+		resolved, err := r.resolve(map[string]interface{}{"$ref": pointer}, path, doc)
+		if err != nil {
+			return nil, err
+		}
+		schema := object(resolved)
+		delete(schema, "ref")
+		api.Schemas = append(api.Schemas, Schema{Name: name, Schema: schema})
+	}
+
+	return api, nil
+}
+
+func newOperation(path, method string, op map[string]interface{}, pathParams []interface{}) (Operation, error) {
+	operation := Operation{
+		ID:          str(op["operationId"]),
+		Method:      strings.ToUpper(method),
+		Path:        path,
+		Summary:     str(op["summary"]),
+		Description: str(op["description"]),
+		Tags:        []string{},
+		Deprecated:  op["deprecated"] == true,
+		Parameters:  []Parameter{},
+		Responses:   []Response{},
+	}
+	if operation.ID == "" {
+		operation.ID = generateID(method, path)
+	}
+	for _, tag := range list(op["tags"]) {
+		operation.Tags = append(operation.Tags, str(tag))
+	}
+
+	// parameters of the operation override parameters of the path with the same name and location
+	params := make(map[string]int)
+	for _, p := range append(pathParams, list(op["parameters"])...) {
+		param := object(p)
+		parameter := Parameter{
+			Name:        str(param["name"]),
+			In:          str(param["in"]),
+			Required:    param["required"] == true,
+			Description: str(param["description"]),
+			Schema:      object(param["schema"]),
+		}
+		if parameter.Name == "" {
+			return operation, fmt.Errorf("Parameter without a name in %s %s", operation.Method, path)
+		}
+		key := parameter.In + ":" + parameter.Name
+		if i, ok := params[key]; ok {
+			operation.Parameters[i] = parameter
+		} else {
+			params[key] = len(operation.Parameters)
+			operation.Parameters = append(operation.Parameters, parameter)
+		}
+	}
+
+	if body, ok := op["requestBody"]; ok {
+		bodyMap := object(body)
+		contentType, schema := content(object(bodyMap["content"]))
+		operation.RequestBody = &Body{
+			Required:    bodyMap["required"] == true,
+			Description: str(bodyMap["description"]),
+			ContentType: contentType,
+			Schema:      schema,
+		}
+	}
+
+	responses := object(op["responses"])
+	for _, status := range sortedKeys(responses) {
+		response := object(responses[status])
+		contentType, schema := content(object(response["content"]))
+		operation.Responses = append(operation.Responses, Response{
+			Status:      status,
+			Description: str(response["description"]),
+			ContentType: contentType,
+			Schema:      schema,
+		})
+	}
+
+	return operation, nil
+}
+
+// content returns the preferred media type and its schema.
+func content(media map[string]interface{}) (string, map[string]interface{}) {
+	if len(media) == 0 {
+		return "", nil
+	}
+	contentType := "application/json"
+	if _, ok := media[contentType]; !ok {
+		contentType = sortedKeys(media)[0]
+	}
+	return contentType, object(object(media[contentType])["schema"])
+}
+
+// generateID generates an operation ID from the method and the path, e.g. getUsersId for GET /users/{id}.
+func generateID(method, path string) string {
+	id := method
+	for _, part := range nonAlnum.Split(path, -1) {
+		if part != "" {
+			id += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return id
+}
+
+func object(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func list(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
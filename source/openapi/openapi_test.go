@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/lovromazgon/chew"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile(t *testing.T) {
+	api, err := ReadFile("testdata/petstore.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "Pet Store", api.Title)
+	assert.Equal(t, []string{"https://example.com/v1"}, api.Servers)
+	assert.Len(t, api.Operations, 3)
+
+	list := api.Operations[0]
+	assert.Equal(t, "listPets", list.ID)
+	assert.Equal(t, "GET", list.Method)
+	assert.Equal(t, []Parameter{{Name: "limit", In: "query", Schema: map[string]interface{}{"type": "integer"}}}, list.Parameters)
+	assert.Equal(t, "200", list.Responses[0].Status)
+	items := list.Responses[0].Schema["items"].(map[string]interface{})
+	assert.Equal(t, "Pet", items["ref"])
+	assert.Equal(t, float64(30), items["properties"].(map[string]interface{})["age"].(map[string]interface{})["maximum"])
+
+	create := api.Operations[1]
+	assert.Equal(t, "postPets", create.ID)
+	assert.Equal(t, "application/json", create.RequestBody.ContentType)
+	assert.True(t, create.RequestBody.Required)
+	assert.Equal(t, "Pet", create.RequestBody.Schema["ref"])
+	assert.Equal(t, "default", create.Responses[1].Status)
+	assert.Equal(t, "Error", create.Responses[1].Description)
+
+	get := api.Operations[2]
+	assert.Equal(t, "getPetsId", get.ID)
+	assert.True(t, get.Deprecated)
+	assert.Len(t, get.Parameters, 2)
+	assert.Equal(t, "Include details", get.Parameters[1].Description)
+
+	assert.Len(t, api.Schemas, 1)
+	pet := api.Schemas[0]
+	assert.Equal(t, "Pet", pet.Name)
+	assert.NotContains(t, pet.Schema, "ref")
+	parent := pet.Schema["properties"].(map[string]interface{})["parent"]
+	assert.Equal(t, map[string]interface{}{"ref": "Pet"}, parent)
+}
+
+func TestReadFile_RefSiblings(t *testing.T) {
+	api, err := ReadFile("testdata/siblings.yaml")
+	require.NoError(t, err)
+	require.Len(t, api.Operations, 1)
+
+	// the local $ref next to the reference to common.yaml is resolved in siblings.yaml
+	schema := api.Operations[0].Responses[0].Schema
+	assert.Equal(t, "array", schema["type"])
+	assert.Equal(t, "List of pets", schema["description"])
+	items := schema["items"].(map[string]interface{})
+	assert.Equal(t, "Pet", items["ref"])
+	assert.Equal(t, "object", items["type"])
+}
+
+func TestLoad(t *testing.T) {
+	chewable, err := Load("testdata/petstore.yaml", Options{
+		OperationTemplates: map[string]string{"handler": "{{ .id }}.go"},
+		SchemaTemplates:    map[string]string{"model": "{{ .name }}.go"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, chewable.Data, 4)
+	assert.Equal(t, "listPets", chewable.Data[0].Local["id"])
+	assert.Equal(t, "Pet", chewable.Data[3].Local["name"])
+	assert.Equal(t, "Pet Store", chewable.Global["api"].(map[string]interface{})["title"])
+}
+
+func TestDecoder(t *testing.T) {
+	decoder := &Decoder{Options: Options{OperationTemplates: map[string]string{"handler": "{{ .id }}.go"}}}
+	chew.RegisterDecoder(".openapi", decoder)
+	defer chew.RegisterDecoder(".openapi", nil)
+
+	chewable, err := chew.LoadChewableWithOptions("testdata/petstore.yaml", chew.LoadOptions{Format: "openapi"})
+	assert.NoError(t, err)
+	assert.Len(t, chewable.Data, 3)
+
+	_, err = decoder.Decode([]byte("data: []\nname: plain"))
+	assert.EqualError(t, err, "Data is not an OpenAPI document, the field 'openapi' is missing")
+}
@@ -0,0 +1,487 @@
+// Package protobuf reads serialized protobuf descriptor sets (created with `protoc --descriptor_set_out`
+// or `buf build`) and provides the described files, messages, enums and services as data for Chew, so
+// templates can act as a lightweight protoc plugin.
+//
+// The files are stored in the global field 'files'. For convenience all messages (including nested
+// messages), enums and services of all files are also stored in the global fields 'messages', 'enums'
+// and 'services':
+//
+//	{
+//	  "files": [{"name": "shop/order.proto", "package": "shop", "go_package": "example.com/shop", "syntax": "proto3", "messages": [...], ...}],
+//	  "messages": [
+//	    {
+//	      "name": "Order", "full_name": "shop.Order", "file": "shop/order.proto", "package": "shop", "doc": "An order.",
+//	      "fields": [{"name": "id", "json_name": "id", "number": 1, "type": "string", "type_name": "", "label": "optional", "repeated": false, "map": false, ...}],
+//	      "oneofs": [], "messages": [], "enums": []
+//	    }
+//	  ],
+//	  "enums": [{"name": "Status", "full_name": "shop.Order.Status", "values": [{"name": "NEW", "number": 0, "doc": ""}], ...}],
+//	  "services": [{"name": "Shop", "full_name": "shop.Shop", "methods": [{"name": "Get", "input_type": "shop.GetRequest", "output_type": "shop.Order", ...}], ...}]
+//	}
+//
+// Comments are only available if the descriptor set contains source info (protoc --include_source_info).
+// Map entry messages generated by protoc are not listed as messages, map fields have the type "map" and
+// contain the types of their keys and values.
+package protobuf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/lovromazgon/chew"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Field numbers in descriptor.proto, used in the paths of source code locations.
+const (
+	fileMessageTypeField   = 4
+	fileEnumTypeField      = 5
+	fileServiceField       = 6
+	messageFieldField      = 2
+	messageNestedTypeField = 3
+	messageEnumTypeField   = 4
+	enumValueField         = 2
+	serviceMethodField     = 2
+)
+
+// Options define which files are used and which templates are executed for messages and services.
+type Options struct {
+	// Files contains the names of the files which are used (e.g. the files passed to protoc without their
+	// imports), all files are used if empty
+	Files []string
+	// MessageTemplates are executed for every message (including nested messages), if not empty
+	MessageTemplates map[string]string
+	// ServiceTemplates are executed for every service, if not empty
+	ServiceTemplates map[string]string
+}
+
+// Set contains the described files and all their messages, enums and services.
+type Set struct {
+	Files    []File    `json:"files"`
+	Messages []Message `json:"messages"`
+	Enums    []Enum    `json:"enums"`
+	Services []Service `json:"services"`
+}
+
+// File is a .proto file.
+type File struct {
+	Name         string    `json:"name"`
+	Package      string    `json:"package"`
+	GoPackage    string    `json:"go_package"`
+	Syntax       string    `json:"syntax"`
+	Dependencies []string  `json:"dependencies"`
+	Messages     []Message `json:"messages"`
+	Enums        []Enum    `json:"enums"`
+	Services     []Service `json:"services"`
+}
+
+// Message is a message, Messages and Enums contain the nested messages and enums.
+type Message struct {
+	Name     string    `json:"name"`
+	FullName string    `json:"full_name"`
+	File     string    `json:"file"`
+	Package  string    `json:"package"`
+	Doc      string    `json:"doc"`
+	Fields   []Field   `json:"fields"`
+	Oneofs   []string  `json:"oneofs"`
+	Messages []Message `json:"messages"`
+	Enums    []Enum    `json:"enums"`
+}
+
+// Field is a field of a message. Type is the protobuf type (e.g. "string", "int64", "message", "enum" or
+// "map"), TypeName contains the full name of the message or enum type. For maps KeyType and ValueType
+// contain the types of keys and values, ValueTypeName the full name of the message or enum type of values.
+// Oneof contains the name of the oneof the field belongs to, Optional is true for proto3 optional fields.
+type Field struct {
+	Name          string `json:"name"`
+	JSONName      string `json:"json_name"`
+	Number        int32  `json:"number"`
+	Type          string `json:"type"`
+	TypeName      string `json:"type_name"`
+	Label         string `json:"label"`
+	Repeated      bool   `json:"repeated"`
+	Map           bool   `json:"map"`
+	KeyType       string `json:"key_type"`
+	ValueType     string `json:"value_type"`
+	ValueTypeName string `json:"value_type_name"`
+	Oneof         string `json:"oneof"`
+	Optional      bool   `json:"optional"`
+	Doc           string `json:"doc"`
+}
+
+// Enum is an enum.
+type Enum struct {
+	Name     string      `json:"name"`
+	FullName string      `json:"full_name"`
+	File     string      `json:"file"`
+	Package  string      `json:"package"`
+	Doc      string      `json:"doc"`
+	Values   []EnumValue `json:"values"`
+}
+
+// EnumValue is a value of an enum.
+type EnumValue struct {
+	Name   string `json:"name"`
+	Number int32  `json:"number"`
+	Doc    string `json:"doc"`
+}
+
+// Service is a service.
+type Service struct {
+	Name     string   `json:"name"`
+	FullName string   `json:"full_name"`
+	File     string   `json:"file"`
+	Package  string   `json:"package"`
+	Doc      string   `json:"doc"`
+	Methods  []Method `json:"methods"`
+}
+
+// Method is a method of a service, InputType and OutputType contain the full names of the messages.
+type Method struct {
+	Name            string `json:"name"`
+	InputType       string `json:"input_type"`
+	OutputType      string `json:"output_type"`
+	ClientStreaming bool   `json:"client_streaming"`
+	ServerStreaming bool   `json:"server_streaming"`
+	Doc             string `json:"doc"`
+}
+
+// Decoder is a chew.FileDecoder for serialized descriptor sets, which can be registered for file extensions
+// (e.g. with chew.RegisterDecoder(".pb", &protobuf.Decoder{})). The field 'data' is always defined, it is
+// empty if no templates are defined in Options.
+type Decoder struct {
+	Options Options
+}
+
+// DecodeFile reads the descriptor set with the provided path.
+func (d *Decoder) DecodeFile(path string) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return d.Decode(data)
+}
+
+// Decode decodes the serialized descriptor set.
+func (d *Decoder) Decode(data []byte) (interface{}, error) {
+	set, err := Parse(data, d.Options)
+	if err != nil {
+		return nil, err
+	}
+	chewable, err := NewChewable(set, d.Options)
+	if err != nil {
+		return nil, err
+	}
+	return chewable.ToDoc(), nil
+}
+
+// Load reads the descriptor set with the provided path and returns it as a Chewable.
+func Load(path string, opts Options) (*chew.Chewable, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set, err := Parse(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse %s: %v", path, err)
+	}
+	return NewChewable(set, opts)
+}
+
+// Parse decodes the serialized FileDescriptorSet and returns the files selected by Options.Files.
+func Parse(data []byte, opts Options) (*Set, error) {
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &fds); err != nil {
+		return nil, err
+	}
+	return NewSet(&fds, opts)
+}
+
+// NewSet converts the FileDescriptorSet and returns the files selected by Options.Files.
+func NewSet(fds *descriptorpb.FileDescriptorSet, opts Options) (*Set, error) {
+	set := &Set{
+		Files:    []File{},
+		Messages: []Message{},
+		Enums:    []Enum{},
+		Services: []Service{},
+	}
+
+	// map entries are needed to resolve map fields, also if they are defined in other files
+	mapEntries := make(map[string]*descriptorpb.DescriptorProto)
+	for _, fd := range fds.GetFile() {
+		collectMapEntries(fd.GetPackage(), fd.GetMessageType(), mapEntries)
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range opts.Files {
+		selected[name] = false
+	}
+
+	for _, fd := range fds.GetFile() {
+		if _, ok := selected[fd.GetName()]; len(opts.Files) > 0 && !ok {
+			continue
+		}
+		selected[fd.GetName()] = true
+
+		c := &converter{
+			file:       fd,
+			comments:   comments(fd),
+			mapEntries: mapEntries,
+		}
+		file := c.convert()
+		set.Files = append(set.Files, file)
+
+		set.Messages = append(set.Messages, flattenMessages(file.Messages)...)
+		set.Enums = append(set.Enums, file.Enums...)
+		for _, message := range flattenMessages(file.Messages) {
+			set.Enums = append(set.Enums, message.Enums...)
+		}
+		set.Services = append(set.Services, file.Services...)
+	}
+
+	for _, name := range opts.Files {
+		if !selected[name] {
+			return nil, fmt.Errorf("Could not find file %s in the descriptor set", name)
+		}
+	}
+	return set, nil
+}
+
+// NewChewable returns the Set as a Chewable. One ChewableData is created for every message if
+// Options.MessageTemplates is not empty and for every service if Options.ServiceTemplates is not empty.
+func NewChewable(set *Set, opts Options) (*chew.Chewable, error) {
+	global, err := chew.ToMap(set)
+	if err != nil {
+		return nil, err
+	}
+
+	chewable := &chew.Chewable{Global: global}
+	if len(opts.MessageTemplates) > 0 {
+		entries, err := chew.NewEntries(set.Messages, opts.MessageTemplates)
+		if err != nil {
+			return nil, err
+		}
+		chewable.Data = append(chewable.Data, entries...)
+	}
+	if len(opts.ServiceTemplates) > 0 {
+		entries, err := chew.NewEntries(set.Services, opts.ServiceTemplates)
+		if err != nil {
+			return nil, err
+		}
+		chewable.Data = append(chewable.Data, entries...)
+	}
+	return chewable, nil
+}
+
+// flattenMessages returns the messages and all their nested messages.
+func flattenMessages(messages []Message) []Message {
+	var flat []Message
+	for _, message := range messages {
+		flat = append(flat, message)
+		flat = append(flat, flattenMessages(message.Messages)...)
+	}
+	return flat
+}
+
+func collectMapEntries(scope string, messages []*descriptorpb.DescriptorProto, mapEntries map[string]*descriptorpb.DescriptorProto) {
+	for _, message := range messages {
+		name := join(scope, message.GetName())
+		if message.GetOptions().GetMapEntry() {
+			mapEntries[name] = message
+		}
+		collectMapEntries(name, message.GetNestedType(), mapEntries)
+	}
+}
+
+// comments returns the leading comments of the file by the path of the described element.
+func comments(fd *descriptorpb.FileDescriptorProto) map[string]string {
+	comments := make(map[string]string)
+	for _, location := range fd.GetSourceCodeInfo().GetLocation() {
+		if location.LeadingComments == nil {
+			continue
+		}
+		comments[pathKey(location.GetPath())] = strings.TrimSpace(location.GetLeadingComments())
+	}
+	return comments
+}
+
+func pathKey(path []int32) string {
+	return strings.Trim(fmt.Sprint(path), "[]")
+}
+
+// converter converts the descriptor of a file.
+type converter struct {
+	file       *descriptorpb.FileDescriptorProto
+	comments   map[string]string
+	mapEntries map[string]*descriptorpb.DescriptorProto
+}
+
+func (c *converter) convert() File {
+	fd := c.file
+	file := File{
+		Name:         fd.GetName(),
+		Package:      fd.GetPackage(),
+		GoPackage:    fd.GetOptions().GetGoPackage(),
+		Syntax:       fd.GetSyntax(),
+		Dependencies: append([]string{}, fd.GetDependency()...),
+		Messages:     []Message{},
+		Enums:        []Enum{},
+		Services:     []Service{},
+	}
+	if file.Syntax == "" {
+		file.Syntax = "proto2"
+	}
+
+	for i, message := range fd.GetMessageType() {
+		file.Messages = append(file.Messages, c.message(fd.GetPackage(), message, []int32{fileMessageTypeField, int32(i)}))
+	}
+	for i, enum := range fd.GetEnumType() {
+		file.Enums = append(file.Enums, c.enum(fd.GetPackage(), enum, []int32{fileEnumTypeField, int32(i)}))
+	}
+	for i, service := range fd.GetService() {
+		file.Services = append(file.Services, c.service(service, []int32{fileServiceField, int32(i)}))
+	}
+	return file
+}
+
+func (c *converter) message(scope string, md *descriptorpb.DescriptorProto, path []int32) Message {
+	fullName := join(scope, md.GetName())
+	message := Message{
+		Name:     md.GetName(),
+		FullName: fullName,
+		File:     c.file.GetName(),
+		Package:  c.file.GetPackage(),
+		Doc:      c.comments[pathKey(path)],
+		Fields:   []Field{},
+		Oneofs:   []string{},
+		Messages: []Message{},
+		Enums:    []Enum{},
+	}
+
+	for _, oneof := range md.GetOneofDecl() {
+		message.Oneofs = append(message.Oneofs, oneof.GetName())
+	}
+	for i, fd := range md.GetField() {
+		field := c.field(fd, appendPath(path, messageFieldField, i))
+		if fd.OneofIndex != nil {
+			if field.Optional {
+				// proto3 optional fields are wrapped in synthetic oneofs
+				message.Oneofs = removeString(message.Oneofs, md.GetOneofDecl()[fd.GetOneofIndex()].GetName())
+			} else {
+				field.Oneof = md.GetOneofDecl()[fd.GetOneofIndex()].GetName()
+			}
+		}
+		message.Fields = append(message.Fields, field)
+	}
+	for i, nested := range md.GetNestedType() {
+		if nested.GetOptions().GetMapEntry() {
+			continue
+		}
+		message.Messages = append(message.Messages, c.message(fullName, nested, appendPath(path, messageNestedTypeField, i)))
+	}
+	for i, enum := range md.GetEnumType() {
+		message.Enums = append(message.Enums, c.enum(fullName, enum, appendPath(path, messageEnumTypeField, i)))
+	}
+	return message
+}
+
+func (c *converter) field(fd *descriptorpb.FieldDescriptorProto, path []int32) Field {
+	field := Field{
+		Name:     fd.GetName(),
+		JSONName: fd.GetJsonName(),
+		Number:   fd.GetNumber(),
+		Type:     typeName(fd.GetType()),
+		TypeName: strings.TrimPrefix(fd.GetTypeName(), "."),
+		Label:    strings.ToLower(strings.TrimPrefix(fd.GetLabel().String(), "LABEL_")),
+		Repeated: fd.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED,
+		Optional: fd.GetProto3Optional(),
+		Doc:      c.comments[pathKey(path)],
+	}
+
+	if entry, ok := c.mapEntries[field.TypeName]; ok && field.Repeated {
+		field.Type = "map"
+		field.TypeName = ""
+		field.Repeated = false
+		field.Map = true
+		for _, entryField := range entry.GetField() {
+			switch entryField.GetName() {
+			case "key":
+				field.KeyType = typeName(entryField.GetType())
+			case "value":
+				field.ValueType = typeName(entryField.GetType())
+				field.ValueTypeName = strings.TrimPrefix(entryField.GetTypeName(), ".")
+			}
+		}
+	}
+	return field
+}
+
+func (c *converter) enum(scope string, ed *descriptorpb.EnumDescriptorProto, path []int32) Enum {
+	enum := Enum{
+		Name:     ed.GetName(),
+		FullName: join(scope, ed.GetName()),
+		File:     c.file.GetName(),
+		Package:  c.file.GetPackage(),
+		Doc:      c.comments[pathKey(path)],
+		Values:   []EnumValue{},
+	}
+	for i, value := range ed.GetValue() {
+		enum.Values = append(enum.Values, EnumValue{
+			Name:   value.GetName(),
+			Number: value.GetNumber(),
+			Doc:    c.comments[pathKey(appendPath(path, enumValueField, i))],
+		})
+	}
+	return enum
+}
+
+func (c *converter) service(sd *descriptorpb.ServiceDescriptorProto, path []int32) Service {
+	service := Service{
+		Name:     sd.GetName(),
+		FullName: join(c.file.GetPackage(), sd.GetName()),
+		File:     c.file.GetName(),
+		Package:  c.file.GetPackage(),
+		Doc:      c.comments[pathKey(path)],
+		Methods:  []Method{},
+	}
+	for i, md := range sd.GetMethod() {
+		service.Methods = append(service.Methods, Method{
+			Name:            md.GetName(),
+			InputType:       strings.TrimPrefix(md.GetInputType(), "."),
+			OutputType:      strings.TrimPrefix(md.GetOutputType(), "."),
+			ClientStreaming: md.GetClientStreaming(),
+			ServerStreaming: md.GetServerStreaming(),
+			Doc:             c.comments[pathKey(appendPath(path, serviceMethodField, i))],
+		})
+	}
+	return service
+}
+
+// typeName returns the name of the type in lowercase without the prefix, e.g. "int64" for TYPE_INT64.
+func typeName(t descriptorpb.FieldDescriptorProto_Type) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "TYPE_"))
+}
+
+func join(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func appendPath(path []int32, field int32, index int) []int32 {
+	p := make([]int32, len(path), len(path)+2)
+	copy(p, path)
+	return append(p, field, int32(index))
+}
+
+func removeString(list []string, s string) []string {
+	for i, elem := range list {
+		if elem == s {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
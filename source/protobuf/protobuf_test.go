@@ -0,0 +1,139 @@
+package protobuf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func testDescriptorSet() *descriptorpb.FileDescriptorSet {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	return &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("common.proto"),
+				Package: proto.String("common"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{Name: proto.String("Money")},
+				},
+			},
+			{
+				Name:       proto.String("shop/order.proto"),
+				Package:    proto.String("shop"),
+				Syntax:     proto.String("proto3"),
+				Dependency: []string{"common.proto"},
+				Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/shop")},
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Order"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{Name: proto.String("id"), JsonName: proto.String("id"), Number: proto.Int32(1), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+							{Name: proto.String("total"), JsonName: proto.String("total"), Number: proto.Int32(2), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".common.Money")},
+							{Name: proto.String("status"), JsonName: proto.String("status"), Number: proto.Int32(3), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum(), TypeName: proto.String(".shop.Order.Status")},
+							{Name: proto.String("labels"), JsonName: proto.String("labels"), Number: proto.Int32(4), Label: repeated, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".shop.Order.LabelsEntry")},
+							{Name: proto.String("lines"), JsonName: proto.String("lines"), Number: proto.Int32(5), Label: repeated, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".shop.Order.Line")},
+							{Name: proto.String("note"), JsonName: proto.String("note"), Number: proto.Int32(6), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), OneofIndex: proto.Int32(0), Proto3Optional: proto.Bool(true)},
+							{Name: proto.String("email"), JsonName: proto.String("email"), Number: proto.Int32(7), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), OneofIndex: proto.Int32(1)},
+						},
+						OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("_note")}, {Name: proto.String("contact")}},
+						NestedType: []*descriptorpb.DescriptorProto{
+							{
+								Name:    proto.String("LabelsEntry"),
+								Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+								Field: []*descriptorpb.FieldDescriptorProto{
+									{Name: proto.String("key"), Number: proto.Int32(1), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+									{Name: proto.String("value"), Number: proto.Int32(2), Label: label, Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()},
+								},
+							},
+							{Name: proto.String("Line")},
+						},
+						EnumType: []*descriptorpb.EnumDescriptorProto{
+							{
+								Name: proto.String("Status"),
+								Value: []*descriptorpb.EnumValueDescriptorProto{
+									{Name: proto.String("NEW"), Number: proto.Int32(0)},
+									{Name: proto.String("PAID"), Number: proto.Int32(1)},
+								},
+							},
+						},
+					},
+				},
+				Service: []*descriptorpb.ServiceDescriptorProto{
+					{
+						Name: proto.String("Shop"),
+						Method: []*descriptorpb.MethodDescriptorProto{
+							{Name: proto.String("Watch"), InputType: proto.String(".shop.Order"), OutputType: proto.String(".shop.Order"), ServerStreaming: proto.Bool(true)},
+						},
+					},
+				},
+				SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+					Location: []*descriptorpb.SourceCodeInfo_Location{
+						{Path: []int32{4, 0}, LeadingComments: proto.String(" An order.\n")},
+						{Path: []int32{4, 0, 2, 0}, LeadingComments: proto.String(" Unique ID.\n")},
+						{Path: []int32{4, 0, 4, 0, 2, 1}, LeadingComments: proto.String(" Paid order.\n")},
+						{Path: []int32{6, 0, 2, 0}, LeadingComments: proto.String(" Streams changes.\n")},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestNewSet(t *testing.T) {
+	set, err := NewSet(testDescriptorSet(), Options{Files: []string{"shop/order.proto"}})
+	assert.NoError(t, err)
+	assert.Len(t, set.Files, 1)
+	assert.Equal(t, "example.com/shop", set.Files[0].GoPackage)
+
+	assert.Len(t, set.Messages, 2)
+	order := set.Messages[0]
+	assert.Equal(t, "shop.Order", order.FullName)
+	assert.Equal(t, "An order.", order.Doc)
+	assert.Equal(t, []string{"contact"}, order.Oneofs)
+	assert.Equal(t, "shop.Order.Line", set.Messages[1].FullName)
+
+	assert.Equal(t, Field{Name: "id", JSONName: "id", Number: 1, Type: "string", Label: "optional", Doc: "Unique ID."}, order.Fields[0])
+	assert.Equal(t, "common.Money", order.Fields[1].TypeName)
+	assert.Equal(t, "enum", order.Fields[2].Type)
+	assert.Equal(t, Field{Name: "labels", JSONName: "labels", Number: 4, Type: "map", Label: "repeated", Map: true, KeyType: "string", ValueType: "int64"}, order.Fields[3])
+	assert.True(t, order.Fields[4].Repeated)
+	assert.True(t, order.Fields[5].Optional)
+	assert.Equal(t, "", order.Fields[5].Oneof)
+	assert.Equal(t, "contact", order.Fields[6].Oneof)
+
+	assert.Len(t, set.Enums, 1)
+	assert.Equal(t, "shop.Order.Status", set.Enums[0].FullName)
+	assert.Equal(t, "Paid order.", set.Enums[0].Values[1].Doc)
+
+	assert.Equal(t, Method{Name: "Watch", InputType: "shop.Order", OutputType: "shop.Order", ServerStreaming: true, Doc: "Streams changes."}, set.Services[0].Methods[0])
+
+	_, err = NewSet(testDescriptorSet(), Options{Files: []string{"missing.proto"}})
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	data, err := proto.Marshal(testDescriptorSet())
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "shop.pb")
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+
+	chewable, err := Load(path, Options{
+		MessageTemplates: map[string]string{"message": "{{ .name }}.go"},
+		ServiceTemplates: map[string]string{"service": "{{ .name }}_service.go"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, chewable.Global["files"], 2)
+	assert.Len(t, chewable.Data, 4)
+	assert.Equal(t, "common.Money", chewable.Data[0].Local["full_name"])
+	assert.Equal(t, "Shop", chewable.Data[3].Local["name"])
+
+	_, err = (&Decoder{}).Decode([]byte("not a descriptor set"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,306 @@
+// Package sqlite reads the schema of a SQLite database and provides it as data for Chew.
+//
+// The schema is stored in the global field 'tables', every table contains its columns, primary key,
+// foreign keys and indexes:
+//
+//	{
+//	  "tables": [
+//	    {
+//	      "name": "orders",
+//	      "columns": [{"name": "id", "type": "INTEGER", "nullable": false, "default": null, "primary_key": true}],
+//	      "primary_key": ["id"],
+//	      "foreign_keys": [{"columns": ["user_id"], "table": "users", "references": ["id"], "on_update": "NO ACTION", "on_delete": "CASCADE"}],
+//	      "indexes": [{"name": "orders_user", "unique": false, "columns": ["user_id"], "origin": "c"}]
+//	    }
+//	  ]
+//	}
+//
+// Optionally one data object is created for every table, which contains the fields of the table and
+// executes the provided templates.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lovromazgon/chew"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Schema is the schema of a SQLite database.
+type Schema struct {
+	Tables []Table `json:"tables"`
+}
+
+// Table is a table in a SQLite database.
+type Table struct {
+	Name        string       `json:"name"`
+	Columns     []Column     `json:"columns"`
+	PrimaryKey  []string     `json:"primary_key"`
+	ForeignKeys []ForeignKey `json:"foreign_keys"`
+	Indexes     []Index      `json:"indexes"`
+}
+
+// Column is a column of a table. Default contains the SQL expression of the default value or nil.
+type Column struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Nullable   bool    `json:"nullable"`
+	Default    *string `json:"default"`
+	PrimaryKey bool    `json:"primary_key"`
+}
+
+// ForeignKey is a foreign key of a table, Columns reference the columns References in Table.
+type ForeignKey struct {
+	Columns    []string `json:"columns"`
+	Table      string   `json:"table"`
+	References []string `json:"references"`
+	OnUpdate   string   `json:"on_update"`
+	OnDelete   string   `json:"on_delete"`
+}
+
+// Index is an index of a table. Origin is 'c' for indexes created with CREATE INDEX, 'u' for indexes
+// created by a UNIQUE constraint and 'pk' for indexes created by a PRIMARY KEY constraint.
+type Index struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Columns []string `json:"columns"`
+	Origin  string   `json:"origin"`
+}
+
+// Decoder is a chew.FileDecoder for SQLite databases, which can be registered for file extensions
+// (e.g. with chew.RegisterDecoder(".sqlite", &sqlite.Decoder{})). If Templates is not empty, one data
+// object which executes Templates is created for every table.
+type Decoder struct {
+	Templates map[string]string
+}
+
+// Load reads the schema of the SQLite database with the provided path and returns it as a Chewable.
+// If templates is not empty, one ChewableData which executes the templates is created for every table.
+func Load(path string, templates map[string]string) (*chew.Chewable, error) {
+	schema, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	global, err := chew.ToMap(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	chewable := &chew.Chewable{Global: global}
+	if len(templates) > 0 {
+		if chewable.Data, err = chew.NewEntries(schema.Tables, templates); err != nil {
+			return nil, err
+		}
+	}
+	return chewable, nil
+}
+
+// DecodeFile reads the schema of the SQLite database with the provided path. The field 'data' is always
+// defined, it is empty if Templates is empty.
+func (d *Decoder) DecodeFile(path string) (interface{}, error) {
+	chewable, err := Load(path, d.Templates)
+	if err != nil {
+		return nil, err
+	}
+	return chewable.ToDoc(), nil
+}
+
+// Decode writes the database to a temporary file and reads its schema.
+func (d *Decoder) Decode(data []byte) (interface{}, error) {
+	file, err := ioutil.TempFile("", "chew-*.sqlite")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.DecodeFile(file.Name())
+}
+
+// ReadFile opens the SQLite database with the provided path in read-only mode and reads its schema.
+func ReadFile(path string) (*Schema, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	// the path is absolute, so a relative path isn't read as the authority of the URI, and escaped, so
+	// characters like ? and # are not interpreted as part of the URI
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	dsn := &url.URL{Scheme: "file", Path: filepath.ToSlash(absPath), RawQuery: "mode=ro"}
+	db, err := sql.Open("sqlite3", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return ReadSchema(db)
+}
+
+// ReadSchema reads the schema of the SQLite database. Internal tables (sqlite_*) are skipped, tables are
+// sorted by name.
+func ReadSchema(db *sql.DB) (*Schema, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{Tables: make([]Table, 0, len(names))}
+	for _, name := range names {
+		table, err := readTable(db, name)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read table %s: %v", name, err)
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+	return schema, nil
+}
+
+func readTable(db *sql.DB, name string) (Table, error) {
+	table := Table{
+		Name:        name,
+		Columns:     []Column{},
+		PrimaryKey:  []string{},
+		ForeignKeys: []ForeignKey{},
+		Indexes:     []Index{},
+	}
+
+	// columns: cid, name, type, notnull, dflt_value, pk (position in the primary key or 0)
+	primaryKey := make(map[int]string)
+	err := query(db, "PRAGMA table_info("+quote(name)+")", func(rows *sql.Rows) error {
+		var cid, notNull, pk int
+		var column Column
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &column.Name, &column.Type, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		column.Nullable = notNull == 0 && pk == 0
+		column.PrimaryKey = pk > 0
+		if dflt.Valid {
+			column.Default = &dflt.String
+		}
+		if pk > 0 {
+			primaryKey[pk] = column.Name
+		}
+		table.Columns = append(table.Columns, column)
+		return nil
+	})
+	if err != nil {
+		return table, err
+	}
+	for i := 1; i <= len(primaryKey); i++ {
+		table.PrimaryKey = append(table.PrimaryKey, primaryKey[i])
+	}
+
+	// foreign keys: id, seq, table, from, to, on_update, on_delete, match (one row per column)
+	foreignKeys := make(map[int]int)
+	err = query(db, "PRAGMA foreign_key_list("+quote(name)+")", func(rows *sql.Rows) error {
+		var id, seq int
+		var refTable, from, onUpdate, onDelete, match string
+		var to sql.NullString
+		if err := rows.Scan(&id, &seq, &refTable, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			return err
+		}
+		i, ok := foreignKeys[id]
+		if !ok {
+			i = len(table.ForeignKeys)
+			foreignKeys[id] = i
+			table.ForeignKeys = append(table.ForeignKeys, ForeignKey{
+				Table:    refTable,
+				OnUpdate: onUpdate,
+				OnDelete: onDelete,
+			})
+		}
+		fk := &table.ForeignKeys[i]
+		fk.Columns = append(fk.Columns, from)
+		// 'to' is NULL if the foreign key references the primary key of the other table
+		fk.References = append(fk.References, to.String)
+		return nil
+	})
+	if err != nil {
+		return table, err
+	}
+
+	// indexes: seq, name, unique, origin, partial
+	err = query(db, "PRAGMA index_list("+quote(name)+")", func(rows *sql.Rows) error {
+		var seq, unique, partial int
+		var index Index
+		if err := rows.Scan(&seq, &index.Name, &unique, &index.Origin, &partial); err != nil {
+			return err
+		}
+		index.Unique = unique == 1
+		table.Indexes = append(table.Indexes, index)
+		return nil
+	})
+	if err != nil {
+		return table, err
+	}
+
+	for i := range table.Indexes {
+		index := &table.Indexes[i]
+		index.Columns = []string{}
+		// index columns: seqno, cid, name (NULL for expressions)
+		err = query(db, "PRAGMA index_info("+quote(index.Name)+")", func(rows *sql.Rows) error {
+			var seqNo, cid int
+			var column sql.NullString
+			if err := rows.Scan(&seqNo, &cid, &column); err != nil {
+				return err
+			}
+			index.Columns = append(index.Columns, column.String)
+			return nil
+		})
+		if err != nil {
+			return table, err
+		}
+	}
+
+	return table, nil
+}
+
+// query executes the query and calls scan for every row.
+func query(db *sql.DB, q string, scan func(*sql.Rows) error) error {
+	rows, err := db.Query(q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func quote(identifier string) string {
+	return `"` + strings.Replace(identifier, `"`, `""`, -1) + `"`
+}
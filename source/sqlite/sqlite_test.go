@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lovromazgon/chew"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createDatabase(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "test.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	name TEXT DEFAULT 'anonymous'
+);
+CREATE TABLE orders (
+	id INTEGER,
+	line INTEGER,
+	user_id INTEGER REFERENCES users (id) ON DELETE CASCADE,
+	PRIMARY KEY (id, line)
+);
+CREATE INDEX orders_user ON orders (user_id);`)
+	require.NoError(t, err)
+	return path
+}
+
+func TestReadFile(t *testing.T) {
+	schema, err := ReadFile(createDatabase(t))
+	require.NoError(t, err)
+	require.Len(t, schema.Tables, 2)
+
+	orders := schema.Tables[0]
+	assert.Equal(t, "orders", orders.Name)
+	assert.Equal(t, []string{"id", "line"}, orders.PrimaryKey)
+	assert.Equal(t, []ForeignKey{
+		{Columns: []string{"user_id"}, Table: "users", References: []string{"id"}, OnUpdate: "NO ACTION", OnDelete: "CASCADE"},
+	}, orders.ForeignKeys)
+	assert.Contains(t, orders.Indexes, Index{Name: "orders_user", Columns: []string{"user_id"}, Origin: "c"})
+
+	users := schema.Tables[1]
+	anonymous := "'anonymous'"
+	assert.Equal(t, []Column{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "email", Type: "TEXT"},
+		{Name: "name", Type: "TEXT", Nullable: true, Default: &anonymous},
+	}, users.Columns)
+	assert.Len(t, users.Indexes, 1)
+	assert.True(t, users.Indexes[0].Unique)
+
+	_, err = ReadFile(filepath.Join(t.TempDir(), "missing.sqlite"))
+	assert.Error(t, err)
+}
+
+func TestReadFile_EscapedPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a?b#c %d")
+	require.NoError(t, os.Mkdir(dir, os.ModePerm))
+	path := filepath.Join(dir, "test.sqlite")
+	require.NoError(t, os.Rename(createDatabase(t), path))
+
+	schema, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Len(t, schema.Tables, 2)
+}
+
+func TestReadFile_RelativePath(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	path, err := filepath.Rel(wd, createDatabase(t))
+	require.NoError(t, err)
+	require.False(t, filepath.IsAbs(path))
+
+	schema, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Len(t, schema.Tables, 2)
+
+	chewable, err := Load(path, nil)
+	require.NoError(t, err)
+	assert.Len(t, chewable.Global["tables"], 2)
+}
+
+func TestLoad(t *testing.T) {
+	chewable, err := Load(createDatabase(t), map[string]string{"dao": "{{ .name }}.go"})
+	require.NoError(t, err)
+	assert.Len(t, chewable.Global["tables"], 2)
+	require.Len(t, chewable.Data, 2)
+	assert.Equal(t, "orders", chewable.Data[0].Local["name"])
+	assert.Equal(t, map[string]string{"dao": "{{ .name }}.go"}, chewable.Data[1].Templates)
+}
+
+func TestDecoder(t *testing.T) {
+	chew.RegisterDecoder(".sqlite", &Decoder{Templates: map[string]string{"dao": "{{ .name }}.go"}})
+	defer chew.RegisterDecoder(".sqlite", nil)
+
+	chewable, err := chew.LoadChewable(createDatabase(t))
+	require.NoError(t, err)
+	require.Len(t, chewable.Data, 2)
+	assert.Equal(t, "users", chewable.Data[1].Local["name"])
+}
@@ -3,11 +3,15 @@ package chew
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/lovromazgon/chew/funcmap"
 )
@@ -19,11 +23,34 @@ var (
 // Template wraps *text/template.Template and adds some additional functionality. It should be
 // created via chew.New. If a manual instantiation is used the method InjectFunctions should be
 // called before processing templates to be able to use all custom chew functions.
+//
+// A template file can extend a layout by starting with the directive {{/* extends "layout" */}}.
+// Such a template only overrides the blocks (defined with {{ block }} in the layout) with its own
+// {{ define }} actions and is executed as the layout. Overridden blocks are only visible to the
+// template that overrides them.
 type Template struct {
 	*template.Template
 	Functions funcmap.Functions
+	// Sources maps the name of every parsed template file to the root folder it was parsed from.
+	Sources map[string]string
+	// Packs contains the manifests of all parsed template packs.
+	Packs []Pack
+	// Verbose receives messages about skipped ChewableData and templates if it is set.
+	Verbose io.Writer
+	// IDField is the field in ChewableData.Local which identifies the ChewableData in the function ref.
+	IDField string
+	// EnvAllowlist contains the names of environment variables which can be read with the function env.
+	EnvAllowlist []string
 
 	injectFuncsOnce sync.Once
+	meta            map[string]*TemplateMeta
+	layouts         map[string]layout
+	resolved        map[string]*template.Template
+	resolvedMutex   sync.Mutex
+	// funcs, entries and index are only set in the copy of the Template created for an execution
+	funcs   map[string]interface{}
+	entries []interface{}
+	index   map[string][]int
 }
 
 // New creates a new chew.Template with the provided name and injects the template functions.
@@ -31,6 +58,8 @@ func New(name string) *Template {
 	ct := &Template{
 		Template:  template.New(name),
 		Functions: funcmap.Global,
+		Sources:   make(map[string]string),
+		IDField:   "id",
 	}
 	ct.InjectFunctions()
 	ct.Option("missingkey=error")
@@ -78,6 +107,29 @@ func (ct *Template) InjectFunctions() {
 					"- indentSize int        : number of spaces to indent this template",
 				Example: "{{ plugins .pluginsArray \"insert_point_1\" \"template_field\" . 2 }}",
 			},
+		}).MustAddFunc(&funcmap.Func{
+			Func: ct.Ref,
+			Doc: funcmap.FuncDoc{
+				Name: "ref",
+				Text: "Use ref to get the data of another object in field 'data' by its id (the field is configurable, default is 'id')." +
+					" The returned data contains global and local fields, the same as when the object is executed.",
+				Example: "{{ (ref .parent_table).name }}",
+			},
+		}).MustAddFunc(&funcmap.Func{
+			Func: ct.Entries,
+			Doc: funcmap.FuncDoc{
+				Name: "entries",
+				Text: "Use entries to iterate over the data of all objects in field 'data' (including objects created by rules and matrices)." +
+					" The data of each object contains global and local fields, the same as when the object is executed.",
+				Example: "{{ range entries }}{{ .name }}{{ end }}",
+			},
+		}).MustAddFunc(&funcmap.Func{
+			Func: ct.Env,
+			Doc: funcmap.FuncDoc{
+				Name:    "env",
+				Text:    "Use env to read an environment variable. Only variables in the allowlist (flag --allow-env) can be read.",
+				Example: "{{ env \"BUILD_NUMBER\" }}",
+			},
 		})
 
 		ct.Funcs(ct.Functions.FuncMap())
@@ -85,11 +137,16 @@ func (ct *Template) InjectFunctions() {
 }
 
 // ParseFolder recursively walks through the provided folder path and parses every template
-// it can find with the template suffix.
+// it can find with the template suffix. If a template with the same name was already parsed
+// it is replaced.
 func (ct *Template) ParseFolder(folderPath string) (*Template, error) {
 	err := filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
-		if strings.Contains(path, templateSuffix) {
-			_, err = ct.ParseFiles(path)
+		if err == nil && strings.Contains(path, templateSuffix) {
+			var content []byte
+			content, err = ioutil.ReadFile(path)
+			if err == nil {
+				err = ct.parseTemplate(filepath.Base(path), content, folderPath)
+			}
 		}
 		return err
 	})
@@ -97,16 +154,209 @@ func (ct *Template) ParseFolder(folderPath string) (*Template, error) {
 	return ct, err
 }
 
+// ParseFolders parses the provided folders in order by calling ParseFolder for each of them.
+// Templates in a later folder replace the same-named templates from an earlier one, so shared
+// template libraries can be listed first and overridden by project specific folders.
+// Paths pointing to a template pack (.zip or .tar.gz) are parsed with ParsePack instead.
+func (ct *Template) ParseFolders(folderPaths ...string) (*Template, error) {
+	for _, folderPath := range folderPaths {
+		var err error
+		if IsPack(folderPath) {
+			_, err = ct.ParsePack(folderPath)
+		} else {
+			_, err = ct.ParseFolder(folderPath)
+		}
+		if err != nil {
+			return ct, err
+		}
+	}
+	return ct, nil
+}
+
+// parseTemplate parses the content of a template file as a template with the provided name and
+// remembers the root from which it was loaded. It is the single code path used for templates
+// coming from folders and template packs. The front matter is stripped from the content and stored
+// as TemplateMeta. Templates which extend a layout are stored separately and resolved when they are
+// executed.
+func (ct *Template) parseTemplate(name string, content []byte, root string) error {
+	ct.resolvedMutex.Lock()
+	ct.resolved = nil
+	ct.resolvedMutex.Unlock()
+
+	meta, content, err := parseFrontMatter(content)
+	if err != nil {
+		return fmt.Errorf("Template %s: %v", name, err)
+	}
+	if meta != nil {
+		if ct.meta == nil {
+			ct.meta = make(map[string]*TemplateMeta)
+		}
+		ct.meta[name] = meta
+	} else {
+		delete(ct.meta, name)
+	}
+
+	if parent := parseExtends(content); parent != "" {
+		if err := ct.addLayout(name, parent, content); err != nil {
+			return err
+		}
+	} else {
+		tmpl := ct.Template
+		if name != ct.Name() {
+			tmpl = ct.New(name)
+		}
+		if _, err := tmpl.Parse(string(content)); err != nil {
+			return err
+		}
+		delete(ct.layouts, name)
+	}
+
+	ct.addSource(name, root)
+	return nil
+}
+
+// TemplateNames returns the sorted names (without the template suffix) of all parsed template files.
+func (ct *Template) TemplateNames() []string {
+	names := make([]string, 0, len(ct.Sources))
+	for name := range ct.Sources {
+		names = append(names, strings.TrimSuffix(name, templateSuffix))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EntryTemplates returns the sorted names (without the template suffix) of the parsed template files which
+// are meant to be executed directly by ChewableData. These are all templates except layouts extended by other
+// templates and templates executed by other templates with the action template or the function
+// indentTemplate. Templates executed with plugins and indentTemplates are chosen in the data, so they are
+// not recognized.
+func (ct *Template) EntryTemplates() []string {
+	used := make(map[string]bool)
+	for _, l := range ct.layouts {
+		used[l.parent] = true
+	}
+	for _, tmpl := range ct.Templates() {
+		if tmpl.Tree != nil {
+			usedTemplates(tmpl.Tree.Root, used)
+		}
+	}
+
+	var names []string
+	for _, name := range ct.TemplateNames() {
+		if !used[name+templateSuffix] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// usedTemplates adds the names (with the template suffix) of the templates executed in the node to used.
+func usedTemplates(node parse.Node, used map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n != nil {
+			for _, child := range n.Nodes {
+				usedTemplates(child, used)
+			}
+		}
+	case *parse.ActionNode:
+		usedTemplates(n.Pipe, used)
+	case *parse.IfNode:
+		usedTemplates(&n.BranchNode, used)
+	case *parse.RangeNode:
+		usedTemplates(&n.BranchNode, used)
+	case *parse.WithNode:
+		usedTemplates(&n.BranchNode, used)
+	case *parse.BranchNode:
+		usedTemplates(n.Pipe, used)
+		usedTemplates(n.List, used)
+		usedTemplates(n.ElseList, used)
+	case *parse.TemplateNode:
+		used[n.Name] = true
+		usedTemplates(n.Pipe, used)
+	case *parse.PipeNode:
+		if n != nil {
+			for _, cmd := range n.Cmds {
+				usedTemplates(cmd, used)
+			}
+		}
+	case *parse.CommandNode:
+		if len(n.Args) > 1 {
+			if fn, ok := n.Args[0].(*parse.IdentifierNode); ok && fn.Ident == "indentTemplate" {
+				if name, ok := n.Args[1].(*parse.StringNode); ok {
+					used[name.Text+templateSuffix] = true
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			usedTemplates(arg, used)
+		}
+	}
+}
+
+// Meta returns the metadata defined in the front matter of the template with the provided name
+// (without the template suffix). If the template has no front matter nil is returned.
+func (ct *Template) Meta(template string) *TemplateMeta {
+	return ct.meta[template+templateSuffix]
+}
+
+// Source returns the root folder from which the template with the provided name (without
+// the template suffix) was parsed. If the template was not parsed from a folder an empty
+// string is returned.
+func (ct *Template) Source(template string) string {
+	return ct.Sources[template+templateSuffix]
+}
+
+func (ct *Template) addSource(name, root string) {
+	if ct.Sources == nil {
+		ct.Sources = make(map[string]string)
+	}
+	ct.Sources[name] = root
+}
+
 // ExecuteChewable loops through all ChewableData in Chewable and executes every Template defined
 // in ChewableData.Templates. The output is written to the supplied chew.Writer, which also gets notified
 // about the desired output filename before every template execution.
-// If template.Template.ExecuteTemplate returns an error the execution stops and returns it.
+// The output filename can contain template actions which are evaluated on the template data. If it is
+// empty, the output pattern from the front matter of the template is used. Defaults from the front
+// matter are added to the data and required fields are validated before executing the template.
+// Templates which extend a layout are executed as their root layout with the overridden blocks.
+// Rules in Chewable are expanded before the execution (see Chewable.Expand) and all ChewableData is indexed
+// for the functions ref and entries. The index belongs to a single execution, so a Template can execute
+// several Chewables concurrently. ChewableData and templates
+// whose conditions are not met are skipped (see ChewableData.When).
+// If a template can't be found or its execution returns an error the execution stops and returns it.
 func (ct *Template) ExecuteChewable(w Writer, c Chewable) error {
-	for _, cd := range c.Data {
+	c, err := c.Expand()
+	if err != nil {
+		return err
+	}
+	exec, err := ct.execution(c)
+	if err != nil {
+		return err
+	}
+	return exec.executeChewable(w, c)
+}
+
+// executeChewable executes the expanded Chewable, ct has to be created with Template.execution.
+func (ct *Template) executeChewable(w Writer, c Chewable) error {
+	for i, cd := range c.Data {
+		if ok, err := ct.when(cd.When, prepareData(c, cd)); err != nil {
+			return fmt.Errorf("Could not evaluate condition of object %d in field 'data': %v", i, err)
+		} else if !ok {
+			ct.logf("Skipping object %d in field 'data', condition '%s' is not met\n", i, cd.When)
+			continue
+		}
+
 		for tmpl, out := range cd.Templates {
-			w.SetOut(out)
-			err := ct.Template.ExecuteTemplate(w, tmpl+templateSuffix, prepareData(c, cd))
-			if err != nil {
+			if ok, err := ct.when(cd.Conditions[tmpl], prepareData(c, cd)); err != nil {
+				return fmt.Errorf("Could not evaluate condition of template %s in object %d: %v", tmpl, i, err)
+			} else if !ok {
+				ct.logf("Skipping template %s in object %d, condition '%s' is not met\n", tmpl, i, cd.Conditions[tmpl])
+				continue
+			}
+
+			if err := ct.executeTemplate(w, tmpl, out, prepareData(c, cd)); err != nil {
 				return err
 			}
 		}
@@ -114,6 +364,78 @@ func (ct *Template) ExecuteChewable(w Writer, c Chewable) error {
 	return nil
 }
 
+// when evaluates the condition on the provided data. An empty condition is always met.
+func (ct *Template) when(condition string, data map[string]interface{}) (bool, error) {
+	if condition == "" {
+		return true, nil
+	}
+	result, err := ct.evaluate("{{ if "+condition+" }}true{{ end }}", data)
+	return result == "true", err
+}
+
+func (ct *Template) logf(format string, args ...interface{}) {
+	if ct.Verbose != nil {
+		fmt.Fprintf(ct.Verbose, format, args...)
+	}
+}
+
+func (ct *Template) executeTemplate(w Writer, tmpl, out string, data map[string]interface{}) error {
+	t, err := ct.lookup(tmpl + templateSuffix)
+	if err != nil {
+		return err
+	}
+
+	meta := ct.meta[tmpl+templateSuffix]
+	if meta != nil {
+		if err := meta.apply(data); err != nil {
+			return fmt.Errorf("Template %s: %v", tmpl, err)
+		}
+		if out == "" {
+			out = meta.Output
+		}
+	}
+
+	out, err = ct.evaluate(out, data)
+	if err != nil {
+		return fmt.Errorf("Could not evaluate output filename of template %s: %v", tmpl, err)
+	}
+
+	if meta != nil && meta.Overwrite != "" && meta.Overwrite != OverwriteAlways {
+		if ew, ok := w.(ExistsWriter); ok && ew.Exists(out) {
+			if meta.Overwrite == OverwriteError {
+				return fmt.Errorf("Template %s: output file %s already exists", tmpl, out)
+			}
+			return nil
+		}
+	}
+
+	w.SetOut(out)
+	return t.Execute(w, data)
+}
+
+// evaluate executes text as an inline template on the provided data. Text without template
+// actions is returned unchanged.
+func (ct *Template) evaluate(text string, data interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	funcs := ct.funcs
+	if funcs == nil {
+		funcs = ct.Functions.FuncMap()
+	}
+	tmpl, err := template.New("inline").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := tmpl.Execute(buffer, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
 // Merges local data from ChewableData and global data from Chewable.
 // If a key exists in both global and local, then local is used.
 func prepareData(c Chewable, cd ChewableData) map[string]interface{} {
@@ -143,9 +465,9 @@ func (ct *Template) IndentTemplate(template string, data interface{}, parent int
 	dataMap["parent"] = parent
 
 	buffer := new(bytes.Buffer)
-	tmpl := ct.Lookup(template + templateSuffix)
-	if tmpl == nil {
-		panic(fmt.Sprintf("Could not find template '%s%s'", template, templateSuffix))
+	tmpl, err := ct.lookup(template + templateSuffix)
+	if err != nil {
+		panic(err)
 	}
 	if err := tmpl.Execute(buffer, dataMap); err != nil {
 		panic(err)
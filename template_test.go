@@ -1,9 +1,11 @@
 package chew
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"log"
@@ -116,3 +118,153 @@ func TestTemplate_Plugins(t *testing.T) {
 
 	assert.Equal(t, expectedIns3, actualIns3)
 }
+
+func TestTemplate_ParseFolders(t *testing.T) {
+	data := map[string]interface{}{
+		"local_var": "test",
+	}
+
+	template := New("main")
+	_, err := template.ParseFolders("test/templates", "test/templates_override")
+	assert.NoError(t, err)
+
+	actual := template.IndentTemplate("test_indentTemplate", data, nil, 2)
+	assert.Equal(t, "  overridden local variable:'test'", actual)
+
+	assert.Equal(t, "test/templates_override", template.Source("test_indentTemplate"))
+	assert.Equal(t, "test/templates", template.Source("test_plugins_main"))
+	assert.Equal(t, []string{
+		"test_indentTemplate",
+		"test_plugins_main",
+		"test_plugins_plugin1_ger",
+		"test_plugins_plugin1_ita",
+		"test_plugins_plugin2",
+	}, template.TemplateNames())
+}
+
+func TestTemplate_EntryTemplates(t *testing.T) {
+	template := New("main")
+	_, err := template.ParseFolder("test/layouts")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"page_a", "page_b", "page_c"}, template.EntryTemplates())
+
+	template = New("main")
+	for name, content := range map[string]string{
+		"main.tmpl":      `{{ range .items }}{{ indentTemplate "item" . $ 2 }}{{ else }}{{ template "empty.tmpl" . }}{{ end }}`,
+		"item.tmpl":      `{{ .name }}`,
+		"empty.tmpl":     `empty`,
+		"plugin.tmpl":    `{{ .name }}`,
+		"wrapper.tmpl":   `{{ with .nested }}{{ plugins .plugins "point" "template" $ 0 }}{{ end }}`,
+		"unrelated.tmpl": `{{ "item" }}`,
+	} {
+		assert.NoError(t, template.parseTemplate(name, []byte(content), ""))
+	}
+	assert.Equal(t, []string{"main", "plugin", "unrelated", "wrapper"}, template.EntryTemplates())
+}
+
+func TestTemplate_ExecuteChewable_When(t *testing.T) {
+	template := New("main")
+	_, err := template.ParseFolder("test/templates")
+	assert.NoError(t, err)
+
+	verbose := new(bytes.Buffer)
+	template.Verbose = verbose
+
+	chewable := Chewable{
+		Global: map[string]interface{}{"audit": false},
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"test_plugins_plugin2": "out"},
+				Local:     map[string]interface{}{"name": "First"},
+				When:      ".audit",
+			},
+			{
+				Templates:  map[string]string{"test_plugins_plugin2": "out"},
+				Local:      map[string]interface{}{"name": "Second"},
+				Conditions: map[string]string{"test_plugins_plugin2": `eq .name "Second"`},
+			},
+			{
+				Templates:  map[string]string{"test_plugins_plugin2": "out"},
+				Local:      map[string]interface{}{"name": "Third"},
+				Conditions: map[string]string{"test_plugins_plugin2": `eq .name "Second"`},
+			},
+		},
+	}
+
+	buffer := new(bytes.Buffer)
+	err = template.ExecuteChewable(WriterWrapper{buffer}, chewable)
+	assert.NoError(t, err)
+	assert.Equal(t, "Plugin Nummer zwei:\nI got inserted by 'Second'", buffer.String())
+	assert.Equal(t, "Skipping object 0 in field 'data', condition '.audit' is not met\n"+
+		"Skipping template test_plugins_plugin2 in object 2, condition 'eq .name \"Second\"' is not met\n", verbose.String())
+
+	chewable.Data[0].When = ".missing"
+	err = template.ExecuteChewable(WriterWrapper{buffer}, chewable)
+	assert.Error(t, err)
+}
+
+func TestTemplate_ExecuteChewable_MemoryWriter(t *testing.T) {
+	template := New("main")
+	_, err := template.ParseFolder("test/templates")
+	assert.NoError(t, err)
+
+	chewable := Chewable{
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"test_plugins_plugin2": "{{ .name }}.txt"},
+				Local:     map[string]interface{}{"name": "First"},
+			},
+			{
+				Templates: map[string]string{"test_plugins_plugin2": "{{ .name }}.txt"},
+				Local:     map[string]interface{}{"name": "Second"},
+			},
+		},
+	}
+
+	w := &MemoryWriter{}
+	err = template.ExecuteChewable(w, chewable)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"First.txt", "Second.txt"}, w.Files())
+	assert.Equal(t, "Plugin Nummer zwei:\nI got inserted by 'Second'", string(w.Content("Second.txt")))
+	assert.True(t, w.Exists("First.txt"))
+	assert.Nil(t, w.Content("Third.txt"))
+
+	// writing the same file again truncates it
+	err = template.ExecuteChewable(w, chewable)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"First.txt", "Second.txt"}, w.Files())
+	assert.Equal(t, "Plugin Nummer zwei:\nI got inserted by 'First'", string(w.Content("First.txt")))
+}
+
+func TestMemoryWriter_Write(t *testing.T) {
+	w := &MemoryWriter{}
+	_, err := w.Write([]byte("content"))
+	assert.Error(t, err)
+}
+
+func TestTemplate_ExecuteChewable_MultiFileWriter(t *testing.T) {
+	template := New("main")
+	assert.NoError(t, template.parseTemplate("dao.tmpl", []byte("{{ .dialect }} {{ .entity.name }}"), ""))
+
+	chewable := Chewable{
+		Data: []ChewableData{{
+			Templates: map[string]string{"dao": "{{ .dialect }}/{{ .entity.name }}.go"},
+			Local: map[string]interface{}{
+				"entities": []interface{}{map[string]interface{}{"name": "user"}, map[string]interface{}{"name": "order"}},
+			},
+			Matrix: Matrix{"dialect": []interface{}{"postgres", "mysql"}, "entity": "entities"},
+		}},
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	err := template.ExecuteChewable(&MultiFileWriter{Out: out}, chewable)
+	assert.NoError(t, err)
+
+	for _, dialect := range []string{"postgres", "mysql"} {
+		for _, entity := range []string{"user", "order"} {
+			content, err := ioutil.ReadFile(filepath.Join(out, dialect, entity+".go"))
+			assert.NoError(t, err)
+			assert.Equal(t, dialect+" "+entity, string(content))
+		}
+	}
+}
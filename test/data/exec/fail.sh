@@ -0,0 +1,3 @@
+#!/bin/sh
+echo "database is not reachable" >&2
+exit 3
@@ -0,0 +1,7 @@
+#!/bin/sh
+# prints the tables as JSON, or as CSV if the first argument is --csv
+if [ "$1" = "--csv" ]; then
+  printf 'name,templates\nusers,table=users.sql\norders,table=orders.sql\n'
+else
+  printf '{"tables": ["users", "orders"], "schema": "public"}\n'
+fi
@@ -0,0 +1,4 @@
+#!/bin/sh
+# the child process inherits stdout, so it keeps the output open after the shell is killed
+sleep 3
+printf '{"tables": []}\n'
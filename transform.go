@@ -0,0 +1,33 @@
+package chew
+
+import (
+	"fmt"
+
+	"github.com/lovromazgon/chew/query"
+)
+
+// Transform applies a transformation to the document form of Chewable, the same form as in the JSON
+// data file (the fields of Global at the top level next to the fields 'data' and 'rules'). The transformation
+// is either a query or an assignment `.path = query` (see package query for the syntax), e.g.:
+//
+//	.entities = .tables | select(.kind == "entity") | sort(.name)
+//
+// The result has to be a valid Chewable document, otherwise an error is returned and Chewable is not changed.
+func (c *Chewable) Transform(transformation string) error {
+	doc, err := query.Transform(c.ToDoc(), transformation)
+	if err != nil {
+		return err
+	}
+
+	global, ok := doc.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Result of transformation '%s' is not an object", transformation)
+	}
+
+	transformed := Chewable{}
+	if err := transformed.fromMap(global); err != nil {
+		return fmt.Errorf("Result of transformation '%s' is not valid: %v", transformation, err)
+	}
+	*c = transformed
+	return nil
+}
@@ -0,0 +1,37 @@
+package chew
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChewable_Transform(t *testing.T) {
+	chewable := &Chewable{
+		Global: map[string]interface{}{
+			"tables": []interface{}{
+				map[string]interface{}{"name": "users", "kind": "entity"},
+				map[string]interface{}{"name": "audit", "kind": "log"},
+			},
+		},
+		Rules: []Rule{{For: "entities", Template: "entity"}},
+	}
+
+	err := chewable.Transform(`.entities = .tables | select(.kind == "entity")`)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "users", "kind": "entity"},
+	}, chewable.Global["entities"])
+	assert.Equal(t, []Rule{{For: "entities", Template: "entity"}}, chewable.Rules)
+
+	err = chewable.Transform(`.data = .tables | map({templates: {table: "out"}, name: .name})`)
+	assert.NoError(t, err)
+	assert.Equal(t, []ChewableData{
+		{Templates: map[string]string{"table": "out"}, Local: map[string]interface{}{"name": "users"}},
+		{Templates: map[string]string{"table": "out"}, Local: map[string]interface{}{"name": "audit"}},
+	}, chewable.Data)
+
+	assert.Error(t, chewable.Transform(".tables"))
+	assert.Error(t, chewable.Transform(`.data = "invalid"`))
+	assert.Len(t, chewable.Data, 2)
+}
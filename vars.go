@@ -0,0 +1,188 @@
+package chew
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var envVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Set stores the value in Chewable.Global on the provided path, where nested fields are separated by dots
+// (e.g. target.schema). Missing objects on the path are created, existing values are overwritten.
+func (c *Chewable) Set(path string, value interface{}) error {
+	if path == "" {
+		return errors.New("Path is empty")
+	}
+	if c.Global == nil {
+		c.Global = make(map[string]interface{})
+	}
+
+	fields := strings.Split(path, ".")
+	current := c.Global
+	for i, field := range fields[:len(fields)-1] {
+		next, ok := current[field]
+		if !ok || next == nil {
+			nextMap := make(map[string]interface{})
+			current[field] = nextMap
+			current = nextMap
+			continue
+		}
+
+		nextMap, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Could not set '%s', field '%s' is not an object", path, strings.Join(fields[:i+1], "."))
+		}
+		current = nextMap
+	}
+
+	current[fields[len(fields)-1]] = value
+	return nil
+}
+
+// ParseAssignment parses an assignment in the form key.path=value. If the value is valid JSON
+// (a number, boolean, null, array, object or quoted string) it is decoded, otherwise the raw
+// string is returned as the value.
+func ParseAssignment(assignment string) (string, interface{}, error) {
+	i := strings.Index(assignment, "=")
+	if i <= 0 {
+		return "", nil, fmt.Errorf("Invalid assignment '%s', expected key.path=value", assignment)
+	}
+
+	path, raw := assignment[:i], assignment[i+1:]
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return path, raw, nil
+	}
+	return path, value, nil
+}
+
+// Interpolate replaces references to variables in all string values of Chewable.Global and ChewableData.Local,
+// in the output filenames in ChewableData.Templates, in the values of ChewableData.Matrix, in the conditions
+// ChewableData.When and ChewableData.Conditions and in the fields of Chewable.Rules. A reference has the form
+// ${NAME} or ${NAME:-default}, the value is returned by lookup (e.g. os.LookupEnv). If a variable doesn't
+// exist and no default value is defined an error is returned.
+func (c *Chewable) Interpolate(lookup func(string) (string, bool)) error {
+	global, err := interpolate(c.Global, lookup)
+	if err != nil {
+		return err
+	}
+	c.Global, _ = global.(map[string]interface{})
+
+	for i := range c.Rules {
+		if err := c.Rules[i].interpolate(lookup); err != nil {
+			return fmt.Errorf("Could not interpolate rule %d in field 'rules': %v", i, err)
+		}
+	}
+
+	for i := range c.Data {
+		if err := c.Data[i].interpolate(lookup); err != nil {
+			return fmt.Errorf("Could not interpolate object %d in field 'data': %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (cd *ChewableData) interpolate(lookup func(string) (string, bool)) error {
+	local, err := interpolate(cd.Local, lookup)
+	if err != nil {
+		return err
+	}
+	cd.Local, _ = local.(map[string]interface{})
+
+	if cd.Matrix != nil {
+		matrix, err := interpolate(map[string]interface{}(cd.Matrix), lookup)
+		if err != nil {
+			return err
+		}
+		cd.Matrix = matrix.(map[string]interface{})
+	}
+
+	if cd.When, err = interpolateString(cd.When, lookup); err != nil {
+		return err
+	}
+	for tmpl, out := range cd.Templates {
+		if cd.Templates[tmpl], err = interpolateString(out, lookup); err != nil {
+			return err
+		}
+	}
+	for tmpl, when := range cd.Conditions {
+		if cd.Conditions[tmpl], err = interpolateString(when, lookup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Rule) interpolate(lookup func(string) (string, bool)) error {
+	for _, field := range []*string{&r.For, &r.Template, &r.Out, &r.When} {
+		var err error
+		if *field, err = interpolateString(*field, lookup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func interpolate(v interface{}, lookup func(string) (string, bool)) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return interpolateString(val, lookup)
+	case map[string]interface{}:
+		if val == nil {
+			return val, nil
+		}
+		interpolated := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			i, err := interpolate(v, lookup)
+			if err != nil {
+				return nil, err
+			}
+			interpolated[k] = i
+		}
+		return interpolated, nil
+	case []interface{}:
+		interpolated := make([]interface{}, len(val))
+		for k, v := range val {
+			i, err := interpolate(v, lookup)
+			if err != nil {
+				return nil, err
+			}
+			interpolated[k] = i
+		}
+		return interpolated, nil
+	}
+	return v, nil
+}
+
+func interpolateString(str string, lookup func(string) (string, bool)) (string, error) {
+	var err error
+	interpolated := envVariable.ReplaceAllStringFunc(str, func(match string) string {
+		groups := envVariable.FindStringSubmatch(match)
+		if value, ok := lookup(groups[1]); ok {
+			return value
+		} else if groups[2] != "" {
+			return groups[3]
+		}
+		if err == nil {
+			err = fmt.Errorf("Variable '%s' is not defined", groups[1])
+		}
+		return match
+	})
+	return interpolated, err
+}
+
+// Env returns the value of the environment variable with the provided name. Only variables listed
+// in Template.EnvAllowlist can be read, for other variables an error is returned.
+func (ct *Template) Env(name string) (string, error) {
+	for _, allowed := range ct.EnvAllowlist {
+		if allowed == name {
+			return os.Getenv(name), nil
+		}
+	}
+	return "", fmt.Errorf("Environment variable '%s' is not in the allowlist", name)
+}
@@ -0,0 +1,153 @@
+package chew
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChewable_Set(t *testing.T) {
+	chewable := &Chewable{
+		Global: map[string]interface{}{
+			"version": float64(1),
+			"target":  map[string]interface{}{"schema": "public"},
+		},
+	}
+
+	assert.NoError(t, chewable.Set("version", "2.0"))
+	assert.NoError(t, chewable.Set("target.schema", "app"))
+	assert.NoError(t, chewable.Set("owner.name", "admin"))
+	assert.Error(t, chewable.Set("version.major", 2))
+	assert.Error(t, chewable.Set("", 2))
+
+	assert.Equal(t, map[string]interface{}{
+		"version": "2.0",
+		"target":  map[string]interface{}{"schema": "app"},
+		"owner":   map[string]interface{}{"name": "admin"},
+	}, chewable.Global)
+}
+
+func TestParseAssignment(t *testing.T) {
+	testCases := []struct {
+		Assignment string
+		Path       string
+		Value      interface{}
+		Error      bool
+	}{
+		{"version=1.2", "version", float64(1.2), false},
+		{`version="1.2"`, "version", "1.2", false},
+		{"target.schema=app", "target.schema", "app", false},
+		{"enabled=true", "enabled", true, false},
+		{"list=[1,2]", "list", []interface{}{float64(1), float64(2)}, false},
+		{"empty=", "empty", "", false},
+		{"a=b=c", "a", "b=c", false},
+		{"=value", "", nil, true},
+		{"value", "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		path, value, err := ParseAssignment(tc.Assignment)
+		if tc.Error {
+			assert.Error(t, err, tc.Assignment)
+			continue
+		}
+		assert.NoError(t, err, tc.Assignment)
+		assert.Equal(t, tc.Path, path, tc.Assignment)
+		assert.Equal(t, tc.Value, value, tc.Assignment)
+	}
+}
+
+func TestChewable_Interpolate(t *testing.T) {
+	env := map[string]string{"OWNER": "admin", "SCHEMA": "app"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	chewable := &Chewable{
+		Global: map[string]interface{}{
+			"owner":   "${OWNER}",
+			"version": float64(1),
+			"nested":  []interface{}{"${SCHEMA}.${MISSING:-default}"},
+		},
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"table": "${SCHEMA}/table.sql"},
+				Local:     map[string]interface{}{"schema": "schema ${SCHEMA}"},
+			},
+		},
+	}
+
+	assert.NoError(t, chewable.Interpolate(lookup))
+	assert.Equal(t, &Chewable{
+		Global: map[string]interface{}{
+			"owner":   "admin",
+			"version": float64(1),
+			"nested":  []interface{}{"app.default"},
+		},
+		Data: []ChewableData{
+			{
+				Templates: map[string]string{"table": "app/table.sql"},
+				Local:     map[string]interface{}{"schema": "schema app"},
+			},
+		},
+	}, chewable)
+
+	chewable = &Chewable{
+		Rules: []Rule{{For: "tables", Template: "table", Out: "${SCHEMA}/{{ .name }}.sql", When: `eq .owner "${OWNER}"`}},
+		Data: []ChewableData{
+			{
+				Templates:  map[string]string{"dao": "dao.go"},
+				Matrix:     Matrix{"dialect": []interface{}{"${SCHEMA}", "mysql"}, "entity": "entities"},
+				When:       `eq .schema "${SCHEMA}"`,
+				Conditions: map[string]string{"dao": `ne .owner "${OWNER}"`},
+			},
+		},
+	}
+	assert.NoError(t, chewable.Interpolate(lookup))
+	assert.Equal(t, &Chewable{
+		Rules: []Rule{{For: "tables", Template: "table", Out: "app/{{ .name }}.sql", When: `eq .owner "admin"`}},
+		Data: []ChewableData{
+			{
+				Templates:  map[string]string{"dao": "dao.go"},
+				Matrix:     Matrix{"dialect": []interface{}{"app", "mysql"}, "entity": "entities"},
+				When:       `eq .schema "app"`,
+				Conditions: map[string]string{"dao": `ne .owner "admin"`},
+			},
+		},
+	}, chewable)
+
+	chewable.Data[0].Conditions["dao"] = "${MISSING}"
+	assert.EqualError(t, chewable.Interpolate(lookup), "Could not interpolate object 0 in field 'data': Variable 'MISSING' is not defined")
+
+	chewable.Global = map[string]interface{}{"missing": "${MISSING}"}
+	assert.EqualError(t, chewable.Interpolate(lookup), "Variable 'MISSING' is not defined")
+}
+
+func TestTemplate_Env(t *testing.T) {
+	os.Setenv("CHEW_TEST_ALLOWED", "allowed")
+	os.Setenv("CHEW_TEST_SECRET", "secret")
+	defer os.Unsetenv("CHEW_TEST_ALLOWED")
+	defer os.Unsetenv("CHEW_TEST_SECRET")
+
+	template := New("main")
+	template.EnvAllowlist = []string{"CHEW_TEST_ALLOWED"}
+	_, err := template.Parse(`
+{{- define "test_allowed.tmpl" }}{{ env "CHEW_TEST_ALLOWED" }}{{ end }}
+{{- define "test_secret.tmpl" }}{{ env "CHEW_TEST_SECRET" }}{{ end }}`)
+	assert.NoError(t, err)
+
+	buffer := new(bytes.Buffer)
+	err = template.ExecuteChewable(WriterWrapper{buffer}, Chewable{
+		Data: []ChewableData{{Templates: map[string]string{"test_allowed": "out"}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "allowed", buffer.String())
+
+	err = template.ExecuteChewable(WriterWrapper{buffer}, Chewable{
+		Data: []ChewableData{{Templates: map[string]string{"test_secret": "out"}}},
+	})
+	assert.Error(t, err)
+}
@@ -1,9 +1,11 @@
 package chew
 
 import (
+	"bytes"
+	"errors"
 	"io"
 	"os"
-	"sync"
+	"path/filepath"
 )
 
 // Writer extends the io.Writer and adds the option to set the output filename.
@@ -17,6 +19,14 @@ type Writer interface {
 	SetOut(filename string)
 }
 
+// ExistsWriter is a Writer which can check if an output file already exists. It is used to respect the
+// overwrite policy defined in the front matter of a template.
+type ExistsWriter interface {
+	Writer
+	// Exists returns true if the output file with the provided filename already exists
+	Exists(filename string) bool
+}
+
 // WriterWrapper is a convenience object to allow wrapping an io.Writer and implement the Writer interface.
 // The SetOut method is empty and does nothing.
 type WriterWrapper struct {
@@ -26,31 +36,93 @@ type WriterWrapper struct {
 // SetOut is empty and does nothing.
 func (WriterWrapper) SetOut(filename string) {}
 
-// MultiFileWriter is a Writer which writes everything to files in the folder Out. If the folder defined in Out
-// doesn't exist it is created before writing to the first file. SetOut has to be called before starting to
+// MultiFileWriter is a Writer which writes everything to files in the folder Out. The folder defined in Out and
+// the subfolders of the output files are created if they don't exist. SetOut has to be called before starting to
 // write to this Writer, so that the file is created or truncated before writing to it.
 type MultiFileWriter struct {
 	*os.File
-	sync.Once
 	Out string
 }
 
 // SetOut sets the filename of the output file into which the succeeding calls to Write will output the content.
-// A file with the provided filename will be created in the folder defined in Out. If the file exists, it will
-// be truncated.
+// A file with the provided filename will be created in the folder defined in Out, the filename can contain
+// subfolders (e.g. "postgres/user.go"). If the file exists, it will be truncated. The previous output file is
+// closed.
 func (w *MultiFileWriter) SetOut(filename string) {
-	w.Once.Do(func() {
-		// check if out folder exists and create it
-		if _, err := os.Stat(w.Out); err != nil {
-			if err := os.MkdirAll(w.Out, os.ModePerm); err != nil {
-				panic(err)
-			}
+	if w.File != nil {
+		if err := w.File.Close(); err != nil {
+			panic(err)
 		}
-	})
+		w.File = nil
+	}
+
+	path := filepath.Join(w.Out, filename)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		panic(err)
+	}
 
 	var err error
-	w.File, err = os.Create(w.Out + "/" + filename)
+	w.File, err = os.Create(path)
 	if err != nil {
 		panic(err)
 	}
 }
+
+// Exists returns true if a file with the provided filename exists in the folder defined in Out.
+func (w *MultiFileWriter) Exists(filename string) bool {
+	_, err := os.Stat(filepath.Join(w.Out, filename))
+	return err == nil
+}
+
+// MemoryWriter is a Writer which collects the content of all output files in memory instead of writing them
+// to disk (e.g. to return them to protoc when running as a plugin). SetOut has to be called before starting
+// to write to this Writer. Calling SetOut with the filename of a file which was already written truncates it.
+type MemoryWriter struct {
+	files   map[string]*bytes.Buffer
+	names   []string
+	current *bytes.Buffer
+}
+
+// SetOut sets the filename of the output file into which the succeeding calls to Write will output the content.
+func (w *MemoryWriter) SetOut(filename string) {
+	if w.files == nil {
+		w.files = make(map[string]*bytes.Buffer)
+	}
+
+	buf, ok := w.files[filename]
+	if !ok {
+		buf = &bytes.Buffer{}
+		w.files[filename] = buf
+		w.names = append(w.names, filename)
+	}
+	buf.Reset()
+	w.current = buf
+}
+
+// Write appends the content to the current output file.
+func (w *MemoryWriter) Write(p []byte) (int, error) {
+	if w.current == nil {
+		return 0, errors.New("Output file is not set, call SetOut before writing")
+	}
+	return w.current.Write(p)
+}
+
+// Exists returns true if a file with the provided filename was already written.
+func (w *MemoryWriter) Exists(filename string) bool {
+	_, ok := w.files[filename]
+	return ok
+}
+
+// Files returns the filenames of all written files in the order in which they were first written.
+func (w *MemoryWriter) Files() []string {
+	return append([]string(nil), w.names...)
+}
+
+// Content returns the content of the file with the provided filename or nil if it wasn't written.
+func (w *MemoryWriter) Content(filename string) []byte {
+	buf, ok := w.files[filename]
+	if !ok {
+		return nil
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,155 @@
+package chew
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultTextKey is the key under which the text of an XML element with attributes or child elements is stored.
+const DefaultTextKey = "#text"
+
+// XMLDecoder decodes XML files into the same tree that is produced when decoding JSON. The root element
+// represents the top-level object, its name is ignored. Elements are mapped as follows:
+//   - an element without attributes and child elements becomes its text, which is trimmed and converted to
+//     a boolean or a number where possible (e.g. <name>users</name> becomes "users", <size>10</size> becomes 10)
+//   - other elements become objects, attributes and child elements are stored under their names (without
+//     the namespace), the trimmed text (if any) is stored under the key TextKey
+//   - repeated child elements with the same name become a list, as do elements named 'data' and 'rules'
+//     and elements with a name listed in Lists, even if they occur only once
+//
+// For example:
+//
+//	<chew package="model">
+//	  <data name="users">
+//	    <templates><entity>users.go</entity></templates>
+//	    <column>id</column>
+//	    <column>email</column>
+//	  </data>
+//	</chew>
+//
+// is decoded into {"package": "model", "data": [{"name": "users", "templates": {"entity": "users.go"}, "column": ["id", "email"]}]}.
+type XMLDecoder struct {
+	// Lists contains the names of elements which are always decoded as lists
+	Lists []string
+	// TextKey is the key for the text of elements with attributes or child elements, DefaultTextKey is used if empty
+	TextKey string
+}
+
+type xmlElement struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlElement
+	text     strings.Builder
+}
+
+// Decode decodes the XML data.
+func (d *XMLDecoder) Decode(data []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var root *xmlElement
+	var stack []*xmlElement
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &xmlElement{name: t.Name.Local, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, element)
+			} else if root != nil {
+				return nil, errors.New("Found more than one root element")
+			} else {
+				root = element
+			}
+			stack = append(stack, element)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("Could not find root element")
+	}
+	return d.convert(root)
+}
+
+func (d *XMLDecoder) convert(element *xmlElement) (interface{}, error) {
+	text := strings.TrimSpace(element.text.String())
+
+	var attrs []xml.Attr
+	for _, attr := range element.attrs {
+		if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	if len(attrs) == 0 && len(element.children) == 0 {
+		return inferValue(text), nil
+	}
+
+	obj := make(map[string]interface{}, len(attrs)+len(element.children))
+	for _, attr := range attrs {
+		obj[attr.Name.Local] = inferValue(attr.Value)
+	}
+
+	// group the child elements by name, keeping the order of first occurrence
+	var names []string
+	children := make(map[string][]interface{})
+	for _, child := range element.children {
+		value, err := d.convert(child)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := children[child.name]; !ok {
+			names = append(names, child.name)
+		}
+		children[child.name] = append(children[child.name], value)
+	}
+
+	for _, name := range names {
+		if _, ok := obj[name]; ok {
+			return nil, fmt.Errorf("Element '%s' has an attribute and a child element named '%s'", element.name, name)
+		}
+		if values := children[name]; len(values) == 1 && !d.isList(name) {
+			obj[name] = values[0]
+		} else {
+			obj[name] = values
+		}
+	}
+
+	if text != "" {
+		textKey := d.TextKey
+		if textKey == "" {
+			textKey = DefaultTextKey
+		}
+		obj[textKey] = text
+	}
+	return obj, nil
+}
+
+// isList returns true if the element with the provided name is always decoded as a list.
+func (d *XMLDecoder) isList(name string) bool {
+	if name == "data" || name == "rules" {
+		return true
+	}
+	for _, list := range d.Lists {
+		if list == name {
+			return true
+		}
+	}
+	return false
+}
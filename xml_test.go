@@ -0,0 +1,47 @@
+package chew
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadChewable_XML(t *testing.T) {
+	chewable, err := LoadChewable("test/data/xml/model.xml")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"package": "model", "version": float64(1)}, chewable.Global)
+	assert.Equal(t, []ChewableData{
+		{
+			Templates: map[string]string{"entity": "{{ .name }}.go"},
+			Local: map[string]interface{}{
+				"name": "users",
+				"column": []interface{}{
+					map[string]interface{}{"nullable": false, "#text": "id"},
+					map[string]interface{}{"nullable": true, "#text": "email"},
+				},
+				"comment": "Registered users",
+			},
+		},
+	}, chewable.Data)
+}
+
+func TestXMLDecoder(t *testing.T) {
+	decoder := &XMLDecoder{Lists: []string{"column"}, TextKey: "value"}
+	doc, err := decoder.Decode([]byte(`<table name="codes"><column>code</column><note lang="en">Text</note><empty/></table>`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":   "codes",
+		"column": []interface{}{"code"},
+		"note":   map[string]interface{}{"lang": "en", "value": "Text"},
+		"empty":  "",
+	}, doc)
+
+	_, err = decoder.Decode([]byte(`<table name="codes"><name>other</name></table>`))
+	assert.Error(t, err)
+
+	_, err = decoder.Decode([]byte(`<table><column></table>`))
+	assert.Error(t, err)
+
+	_, err = decoder.Decode([]byte(``))
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package chew
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLDecoder decodes YAML files into the same tree that is produced when decoding JSON. Mappings become
+// map[string]interface{} (non-string keys are formatted with fmt.Sprint) and all numbers become float64.
+// The structure of the data is the same as in JSON, e.g.:
+//
+//	package: model
+//	data:
+//	  - name: users
+//	    templates: {entity: "{{ .name }}.go"}
+type YAMLDecoder struct{}
+
+// Decode decodes the YAML data.
+func (d *YAMLDecoder) Decode(data []byte) (interface{}, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return normalizeYAML(doc), nil
+}
+
+// normalizeYAML converts maps to map[string]interface{} and numbers to float64.
+func normalizeYAML(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, elem := range val {
+			val[k] = normalizeYAML(elem)
+		}
+		return val
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, elem := range val {
+			m[fmt.Sprint(k)] = normalizeYAML(elem)
+		}
+		return m
+	case []interface{}:
+		for i, elem := range val {
+			val[i] = normalizeYAML(elem)
+		}
+		return val
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case uint64:
+		return float64(val)
+	}
+	return v
+}
@@ -0,0 +1,37 @@
+package chew
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadChewable_YAML(t *testing.T) {
+	chewable, err := LoadChewable("test/data/yaml/model.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"package": "model",
+		"version": float64(1),
+		"codes":   map[string]interface{}{"1": "active"},
+	}, chewable.Global)
+	assert.Equal(t, []ChewableData{
+		{
+			Templates: map[string]string{"entity": "{{ .name }}.go"},
+			Local: map[string]interface{}{
+				"name":    "users",
+				"columns": []interface{}{"id", "email"},
+				"size":    2.5,
+			},
+		},
+	}, chewable.Data)
+}
+
+func TestYAMLDecoder(t *testing.T) {
+	decoder := &YAMLDecoder{}
+	doc, err := decoder.Decode([]byte(`{"openapi": "3.0.0", "list": [1, true, null]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"openapi": "3.0.0", "list": []interface{}{float64(1), true, nil}}, doc)
+
+	_, err = decoder.Decode([]byte("a: [1"))
+	assert.Error(t, err)
+}